@@ -0,0 +1,207 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// isAdmin reports whether the user is the configured bot operator.
+func (s *Service) isAdmin(userID int64) bool {
+	return s.config.AdminUserID != 0 && userID == s.config.AdminUserID
+}
+
+// requireAdmin tells non-admin users that the command is restricted.
+func (s *Service) requireAdmin(ctx context.Context, b *bot.Bot, m *models.Update) bool {
+	if s.isAdmin(m.Message.From.ID) {
+		return true
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   "This command is only available to the admin",
+	})
+	return false
+}
+
+func (s *Service) whoisHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	if !s.requireAdmin(ctx, b, m) {
+		return
+	}
+
+	userID, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/whois")), 10, 64)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /whois <telegramUserID>",
+		})
+		return
+	}
+
+	accessToken, ok := s.store.GetUserAccessToken(userID)
+	token := "not set"
+	if ok {
+		token = maskToken(accessToken)
+	}
+
+	messageCount, err := s.store.GetUserMessageCount(userID)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+		return
+	}
+
+	rows := [][2]string{
+		{"User ID", strconv.FormatInt(userID, 10)},
+		{"Access token", token},
+		{"Locale", valueOrUnset(s.store.GetUserLocale(userID))},
+		{"Timezone", valueOrUnset(s.store.GetUserTimezone(userID))},
+		{"Server URL", valueOrUnset(s.store.GetUserServerURL(userID))},
+		{"Notes created", strconv.Itoa(s.store.GetNotesCreated(userID))},
+		{"Messages saved", strconv.Itoa(messageCount)},
+		{"Created", s.creationTime(userID)},
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    m.Message.Chat.ID,
+		Text:      formatTable([2]string{"Field", "Value"}, rows),
+		ParseMode: models.ParseModeMarkdown,
+	})
+}
+
+func (s *Service) serverStatsHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	if !s.requireAdmin(ctx, b, m) {
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	stats, err := client.GetServerStats()
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Failed to get server stats", err))
+		return
+	}
+
+	rows := [][2]string{
+		{"Notes", strconv.Itoa(stats.TotalNotes)},
+		{"Users", strconv.Itoa(stats.TotalUsers)},
+		{"Storage used", formatBytes(int64(stats.StorageUsed))},
+		{"Version", valueOrUnset(stats.ServerVersion)},
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    m.Message.Chat.ID,
+		Text:      formatTable([2]string{"Field", "Value"}, rows),
+		ParseMode: models.ParseModeMarkdown,
+	})
+}
+
+func (s *Service) adminHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	if !s.requireAdmin(ctx, b, m) {
+		return
+	}
+
+	subcommand, args, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/admin")), " ")
+	switch subcommand {
+	case "list":
+		userIDs, err := s.store.ListAllUsers()
+		if err != nil {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+			return
+		}
+		if len(userIDs) == 0 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: m.Message.Chat.ID,
+				Text:   "No users",
+			})
+			return
+		}
+		rows := make([][2]string, 0, len(userIDs))
+		for _, userID := range userIDs {
+			rows = append(rows, [2]string{strconv.FormatInt(userID, 10), strconv.Itoa(s.store.GetNotesCreated(userID))})
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    m.Message.Chat.ID,
+			Text:      formatTable([2]string{"User ID", "Notes created"}, rows),
+			ParseMode: models.ParseModeMarkdown,
+		})
+	case "broadcast":
+		s.broadcastHandler(ctx, b, m, args)
+	default:
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /admin list or /admin broadcast [--dry-run] <message>",
+		})
+	}
+}
+
+func (s *Service) broadcastHandler(ctx context.Context, b *bot.Bot, m *models.Update, args string) {
+	message, dryRun := strings.CutPrefix(strings.TrimSpace(args), "--dry-run")
+	message = strings.TrimSpace(message)
+	if message == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /admin broadcast [--dry-run] <message>",
+		})
+		return
+	}
+
+	if err := s.Broadcast(ctx, message, dryRun); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(err.Error(), err))
+		return
+	}
+
+	text := "Broadcast sent"
+	if dryRun {
+		text = "Dry run done, the recipients are in the logs"
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+func (s *Service) cleanupCacheHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	if !s.requireAdmin(ctx, b, m) {
+		return
+	}
+
+	// Other entries, e.g. the confirmations reactions rely on, are kept.
+	count := s.cache.deleteExpired() + s.store.DeleteMediaGroups(channelMediaGroupPrefix)
+	slog.Info("cleaned up cache", slog.Int("count", count))
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Cleared %d expired entries and channel media groups", count),
+	})
+}
+
+// formatTable renders the rows as a Markdown table in a code block, since
+// Telegram does not render tables.
+func formatTable(header [2]string, rows [][2]string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("```\n| %s | %s |\n| --- | --- |\n", header[0], header[1]))
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("| %s | %s |\n", row[0], strings.ReplaceAll(row[1], "`", "'")))
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// maskToken hides all but the first characters of the token.
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return token[:4] + "…" + token[len(token)-4:]
+}
+
+func valueOrUnset(value string) string {
+	if value == "" {
+		return "not set"
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const maxAttachRedirects = 5
+
+func (s *Service) attachHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	args := strings.Fields(strings.TrimPrefix(m.Message.Text, "/attach"))
+	if len(args) != 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /attach <id> <url>",
+		})
+		return
+	}
+
+	memoId, err := strconv.Atoi(args[0])
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Invalid memo ID",
+		})
+		return
+	}
+
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	data, filename, err := downloadURL(ctx, args[1], s.config.AttachMaxSize)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to download file"))
+		return
+	}
+
+	resource, err := client.UploadFile(data, filename)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to create resource"))
+		return
+	}
+
+	_, err = client.UpsertBlinko(BlinkoItem{
+		ID:          memo.ID,
+		Content:     memo.Content,
+		IsTop:       memo.IsTop,
+		Attachments: []FileInfo{resource},
+	})
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to update memo"))
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Attached %s to %d", filename, memo.ID),
+		ReplyParameters: &models.ReplyParameters{
+			MessageID: m.Message.ID,
+		},
+	})
+}
+
+// downloadURL downloads the file at rawURL, following at most
+// maxAttachRedirects redirects and rejecting files larger than maxSize bytes.
+// Only public addresses are reached, never the host or its network.
+// The filename is taken from the Content-Disposition header or the URL path.
+func downloadURL(ctx context.Context, rawURL string, maxSize int64) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", NewUserError(fmt.Sprintf("Invalid URL %q", rawURL), err)
+	}
+
+	client := newPublicHTTPClient(0)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxAttachRedirects {
+			return errors.Errorf("stopped after %d redirects", maxAttachRedirects)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", NewUserError("Failed to download the file", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", NewUserError(fmt.Sprintf("Download failed with status %s", resp.Status), nil)
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, "", NewUserError(fmt.Sprintf("The file exceeds the size limit of %d bytes", maxSize), nil)
+	}
+
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, "", NewUserError(fmt.Sprintf("The file exceeds the size limit of %d bytes", maxSize), nil)
+	}
+
+	return data, responseFilename(resp), nil
+}
+
+func responseFilename(resp *http.Response) string {
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil && params["filename"] != "" {
+			return path.Base(params["filename"])
+		}
+	}
+	// resp.Request is the final request after redirects
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return "attachment"
+}
@@ -0,0 +1,63 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+const (
+	burstWindow    = 10 * time.Minute
+	burstThreshold = 50
+)
+
+func burstCacheKey(userID int64) string {
+	return fmt.Sprintf("burst:%d", userID)
+}
+
+// recordMemoCreation tracks memo creations of the user in a sliding window.
+// It returns the number of memos in the window when it crosses the burst
+// threshold, 0 otherwise, to pass to reportBurst once s.mutex is released.
+// Callers must hold s.mutex.
+func (s *Service) recordMemoCreation(userID int64) int {
+	now := time.Now()
+
+	var timestamps []time.Time
+	if cached, ok := s.cache.get(burstCacheKey(userID)); ok {
+		for _, t := range cached.([]time.Time) {
+			if now.Sub(t) < burstWindow {
+				timestamps = append(timestamps, t)
+			}
+		}
+	}
+	timestamps = append(timestamps, now)
+	s.cache.set(burstCacheKey(userID), timestamps, burstWindow)
+
+	// Only report when the threshold is crossed to avoid flooding the logs.
+	if len(timestamps) != burstThreshold+1 {
+		return 0
+	}
+	return len(timestamps)
+}
+
+// reportBurst logs the burst of count memos and alerts the admin. It sends
+// a message, so it must not be called with s.mutex held.
+func (s *Service) reportBurst(userID int64, count int) {
+	if count == 0 {
+		return
+	}
+	slog.Warn("suspicious memo creation burst",
+		slog.Int64("userID", userID),
+		slog.Int("count", count),
+		slog.Duration("window", burstWindow),
+	)
+	if s.config.AdminUserID != 0 {
+		s.currentBot().SendMessage(context.Background(), &bot.SendMessageParams{
+			ChatID: s.config.AdminUserID,
+			Text:   fmt.Sprintf("User %d created %d memos within %s", userID, count, burstWindow),
+		})
+	}
+}
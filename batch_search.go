@@ -0,0 +1,117 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"golang.org/x/sync/errgroup"
+)
+
+const maxBatchQueries = 5
+
+const batchSearchUsage = "Usage: /batch_search <query1> | <query2> | <query3>"
+
+// batchSearchHandler runs up to maxBatchQueries searches concurrently and
+// sends the merged results, each prefixed with the queries it matched. The
+// failed queries are reported along with the results of the others.
+func (s *Service) batchSearchHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	var queries []string
+	for _, query := range strings.Split(strings.TrimPrefix(m.Message.Text, "/batch_search"), "|") {
+		if query = sanitizeQuery(query); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	if len(queries) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   batchSearchUsage,
+		})
+		return
+	}
+	if len(queries) > maxBatchQueries {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Too many queries, at most %d are allowed", maxBatchQueries), nil))
+		return
+	}
+	if _, ok := s.authorize(ctx, b, m); !ok {
+		return
+	}
+	userID := m.Message.From.ID
+
+	// Each query gets a client of its own, and a failed query does not
+	// discard the results of the others.
+	results := make([][]BlinkoItem, len(queries))
+	errs := make([]error, len(queries))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, query := range queries {
+		g.Go(func() error {
+			result, err := s.newUserClient(gctx, userID).SearchNotes(SearchParams{Query: query})
+			results[i], errs[i] = result.Items, err
+			return gctx.Err()
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return
+	}
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			slog.Error("failed to search memos", slog.String("query", queries[i]), slog.Any("err", err))
+			failed = append(failed, queries[i])
+		}
+	}
+	if len(failed) == len(queries) {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Failed to search memos", errs[0]))
+		return
+	}
+	if len(failed) > 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("Failed to search: %s", strings.Join(failed, ", ")),
+		})
+	}
+
+	memos := mergeSearchResults(queries, results)
+	limit := s.config.MaxSearchResults
+	truncated := len(memos) > limit
+	if truncated {
+		memos = memos[:limit]
+	}
+	s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, memos)
+	if truncated {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("(results truncated to %d)", limit),
+		})
+	}
+}
+
+// mergeSearchResults deduplicates the results of the queries by memo ID and
+// prefixes the content with the queries that returned the memo, e.g.
+// "[golang, go]".
+func mergeSearchResults(queries []string, results [][]BlinkoItem) []BlinkoItem {
+	var memos []BlinkoItem
+	var matched [][]string
+	index := map[int]int{}
+	for i, items := range results {
+		for _, memo := range items {
+			j, ok := index[memo.ID]
+			if !ok {
+				j = len(memos)
+				index[memo.ID] = j
+				memos = append(memos, memo)
+				matched = append(matched, nil)
+			}
+			matched[j] = append(matched[j], queries[i])
+		}
+	}
+
+	for i := range memos {
+		memos[i].Content = fmt.Sprintf("[%s] %s", strings.Join(matched[i], ", "), memos[i].Content)
+	}
+	return memos
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	blinkogram "github.com/wolfsilver/blinko-telegram"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	service, err := blinkogram.NewService()
 	if err != nil {
 		panic(err)
@@ -6,28 +6,82 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode"
 	"unicode/utf16"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/pkg/errors"
 	"github.com/wolfsilver/blinko-telegram/store"
+	"golang.org/x/time/rate"
 )
 
 type Service struct {
-	bot    *bot.Bot
-	client *BlinkoClient
 	config *Config
 	store  *store.Store
 	cache  *Cache
 
+	// botMutex guards bot and botUsername, replaced when polling rotates
+	// to another token. Use currentBot and currentBotUsername.
+	botMutex    sync.RWMutex
+	bot         *bot.Bot
+	botUsername string
+
+	locales         Locales
+	queue           chan pendingUpdate
+	forwardTemplate *template.Template
+	clientOptions   []BlinkoClientOption
+	shutdownTracing func(context.Context) error
+
+	summaryClient *SummaryClient
+
 	mutex sync.Mutex
+
+	// fileWorkerPool limits the concurrent file uploads to UPLOAD_WORKERS.
+	// attachMutex serializes adding the uploaded files to memos.
+	fileWorkerPool chan struct{}
+	attachMutex    sync.Mutex
+
+	// pinMutex serializes /pin_toggle, which reads the note before updating it.
+	pinMutex sync.Mutex
+
+	limitersMutex sync.Mutex
+	limiters      map[int64]*rate.Limiter
+
+	pollingMutex    sync.Mutex
+	pollingCancel   context.CancelFunc
+	pollingErr      error
+	rateLimitErrors []time.Time
+
+	// handlerCtx is the context of the handlers, cancelled after the
+	// shutdown timeout but not when polling restarts.
+	handlerCtx context.Context
+
+	// work tracks the handlers and workers Start waits for before closing
+	// the store, see beginWork. stopCtx is cancelled by Stop.
+	work         sync.WaitGroup
+	workMutex    sync.Mutex
+	shuttingDown bool
+	stopCtx      context.Context
+	stop         context.CancelFunc
+
+	// botOptions are kept to create the bot again when polling rotates
+	// to BOT_TOKEN_2 or BOT_TOKEN_3. botHTTPClient is the client of the
+	// bot, going through BOT_SOCKS5_PROXY if set, to download files.
+	botOptions    []bot.Option
+	botHTTPClient *http.Client
+	tokenRotator  *tokenRotator
 }
 
 func NewService() (*Service, error) {
@@ -35,30 +89,87 @@ func NewService() (*Service, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get config from env")
 	}
+	// Set before creating the clients, they keep the default logger.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: logLevels[config.LogLevel],
+	})))
 
-	client := NewBlinkoClient(config.ServerAddr)
+	clientOptions, err := blinkoClientOptions(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure blinko client")
+	}
 
 	store := store.NewStore(config.Data)
 	if err := store.Init(); err != nil {
 		return nil, errors.Wrap(err, "failed to init store")
 	}
+	locales, err := loadLocales()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load locales")
+	}
+	shutdownTracing, err := setupTracing(context.Background(), config.OtelEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up tracing")
+	}
+	forwardTemplate, err := template.New("forward").Parse(config.ForwardTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse FORWARD_TEMPLATE")
+	}
+
 	s := &Service{
-		config: config,
-		client: client,
-		store:  store,
-		cache:  NewCache(),
+		config:  config,
+		store:   store,
+		cache:   NewCache(),
+		locales: locales,
+		queue:   make(chan pendingUpdate, config.QueueSize),
+
+		fileWorkerPool: make(chan struct{}, config.UploadWorkers),
+
+		forwardTemplate: forwardTemplate,
+		clientOptions:   clientOptions,
+		shutdownTracing: shutdownTracing,
+		limiters:        map[int64]*rate.Limiter{},
 	}
+	s.stopCtx, s.stop = context.WithCancel(context.Background())
 	s.cache.startGC()
+	if config.SummarizeAPIURL != "" {
+		s.summaryClient = NewSummaryClient(config.SummarizeAPIURL)
+	}
+	// Checked in the background so that an unreachable server does not
+	// delay the startup.
+	go s.checkServerVersion()
 
+	allowedUpdates := bot.AllowedUpdates{
+		"message",
+		"callback_query",
+		"message_reaction",
+	}
+	if config.ChannelID != 0 {
+		allowedUpdates = append(allowedUpdates, "channel_post")
+	}
 	opts := []bot.Option{
 		bot.WithDefaultHandler(s.handler),
 		bot.WithCallbackQueryDataHandler("", bot.MatchTypePrefix, s.callbackQueryHandler),
+		bot.WithErrorsHandler(s.pollingErrorHandler),
+		bot.WithAllowedUpdates(allowedUpdates),
+		bot.WithMiddlewares(s.trackHandler),
 	}
 	if config.BotProxyAddr != "" {
 		opts = append(opts, bot.WithServerURL(config.BotProxyAddr))
 	}
+	s.botHTTPClient = &http.Client{Timeout: botPollTimeout}
+	if config.BotSocks5Proxy != "" {
+		httpClient, err := newSocks5HTTPClient(config.BotSocks5Proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create SOCKS5 proxy client")
+		}
+		opts = append(opts, bot.WithHTTPClient(botPollTimeout, httpClient))
+		s.botHTTPClient = httpClient
+	}
 
-	b, err := bot.New(config.BotToken, opts...)
+	s.botOptions = opts
+	s.tokenRotator = newTokenRotator(config.BotToken, config.BotToken2, config.BotToken3)
+	b, err := s.newBot(s.tokenRotator.current())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create bot")
 	}
@@ -70,6 +181,41 @@ func NewService() (*Service, error) {
 func (s *Service) Start(ctx context.Context) {
 	slog.Info("Blinkogram started")
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.stopCtx, cancel)()
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	s.handlerCtx = handlerCtx
+	s.setupBot(ctx)
+
+	s.startDailyDigest(ctx)
+	s.startQueueDrain(ctx)
+	s.startMetricsServer(ctx)
+	s.startEventWebhook(ctx)
+	s.runPolling(ctx)
+	s.waitForWork(cancelHandlers)
+
+	if err := s.store.Close(); err != nil {
+		slog.Error("failed to close store", slog.Any("err", err))
+	}
+
+	if err := s.shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to flush traces", slog.Any("err", err))
+	}
+}
+
+// setupBot fetches the username and registers the commands and description
+// of the bot. It runs again when polling switches to another bot token.
+func (s *Service) setupBot(ctx context.Context) {
+	me, err := s.currentBot().GetMe(ctx)
+	if err != nil {
+		slog.Error("failed to get bot info", slog.Any("err", err))
+	} else {
+		s.botMutex.Lock()
+		s.botUsername = me.Username
+		s.botMutex.Unlock()
+	}
+
 	// set bot commands
 	commands := []models.BotCommand{
 		{
@@ -80,81 +226,474 @@ func (s *Service) Start(ctx context.Context) {
 			Command:     "search",
 			Description: "Search for the memos",
 		},
+		{
+			Command:     "search_in",
+			Description: "Search the content, a tag or the ID of the memos",
+		},
+		{
+			Command:     "batch_search",
+			Description: "Search several queries separated by |",
+		},
+		{
+			Command:     "logout",
+			Description: "Remove the stored access token",
+		},
+		{
+			Command:     "language",
+			Description: "Change the language of the bot replies",
+		},
+		{
+			Command:     "daily_digest",
+			Description: "Schedule a daily summary of yesterday's memos",
+		},
+		{
+			Command:     "attach",
+			Description: "Attach a file from a URL to a memo",
+		},
+		{
+			Command:     "import",
+			Description: "Import memos from a JSON backup",
+		},
+		{
+			Command:     "duplicate_check",
+			Description: "Find memos with identical content",
+		},
+		{
+			Command:     "note",
+			Description: "Show the content of a memo",
+		},
+		{
+			Command:     "open",
+			Description: "Open a memo in Blinko",
+		},
+		{
+			Command:     "length",
+			Description: "Show the character count of a memo",
+		},
+		{
+			Command:     "word_count",
+			Description: "Show detailed statistics of a memo",
+		},
+		{
+			Command:     "note_size",
+			Description: "Show the total size of the attachments of a memo",
+		},
+		{
+			Command:     "mark_read",
+			Description: "Mark a memo as reviewed",
+		},
+		{
+			Command:     "mark_unread",
+			Description: "Mark a memo as not reviewed",
+		},
+		{
+			Command:     "unread",
+			Description: "List the memos not marked as read",
+		},
+		{
+			Command:     "format",
+			Description: "Link URLs and fix the markdown of a memo",
+		},
+		{
+			Command:     "search_replace",
+			Description: "Replace text in a memo",
+		},
+		{
+			Command:     "mirror",
+			Description: "Copy a memo to another Blinko server",
+		},
+		{
+			Command:     "merge",
+			Description: "Combine two memos into one",
+		},
+		{
+			Command:     "convert",
+			Description: "Turn a memo into a flash or a regular note",
+		},
+		{
+			Command:     "pin_toggle",
+			Description: "Pin or unpin a memo",
+		},
+		{
+			Command:     "pin_all",
+			Description: "Pin all your memos",
+		},
+		{
+			Command:     "unpin_all",
+			Description: "Unpin all your memos",
+		},
+		{
+			Command:     "transform",
+			Description: "Transform the content of new memos",
+		},
+		{
+			Command:     "tag_list",
+			Description: "Show all tags used in your memos",
+		},
+		{
+			Command:     "template",
+			Description: "Save and reuse memo templates",
+		},
+		{
+			Command:     "subscribe",
+			Description: "Get notified of new memos with a tag",
+		},
+		{
+			Command:     "unsubscribe",
+			Description: "Stop notifications for a tag",
+		},
+		{
+			Command:     "notify_on_share",
+			Description: "Toggle notifications when your public notes are viewed",
+		},
+		{
+			Command:     "sandbox",
+			Description: "Preview memos without saving them",
+		},
+		{
+			Command:     "set_server",
+			Description: "Change your Blinko server",
+		},
+		{
+			Command:     "notebook",
+			Description: "Set the notebook new memos are saved to",
+		},
+		{
+			Command:     "streak",
+			Description: "Show your daily memo streak",
+		},
+		{
+			Command:     "me",
+			Description: "Show your usage statistics",
+		},
+		{
+			Command:     "whoami",
+			Description: "Show your Telegram ID and session info",
+		},
+		{
+			Command:     "list_commands",
+			Description: "Show all available commands",
+		},
+		{
+			Command:     "feedback",
+			Description: "Send feedback to the bot operator",
+		},
 	}
-	var err error
-	_, err = s.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: commands})
+	scope := s.commandScope()
+	if s.config.BotCommandScope == "private" {
+		// Drop the commands registered for everyone by previous runs
+		if _, err := s.currentBot().DeleteMyCommands(ctx, &bot.DeleteMyCommandsParams{Scope: &models.BotCommandScopeDefault{}}); err != nil {
+			slog.Error("failed to delete default bot commands", slog.Any("err", err))
+		}
+	}
+	_, err = s.currentBot().SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: commands, Scope: scope})
 	if err != nil {
 		slog.Error("failed to set bot commands", slog.Any("err", err))
 	}
 
-	s.bot.Start(ctx)
+	s.configureBot(ctx)
+}
+
+// forwardAttribution is the data available to FORWARD_TEMPLATE.
+type forwardAttribution struct {
+	Name     string
+	Username string
+	Content  string
+}
+
+const (
+	botDescription = "Save your Telegram messages, photos and files as memos in Blinko.\n\n" +
+		"Send /start <access_token> to sign in, then send anything to save it. " +
+		"Use /search <words> to find memos and /list_commands to see everything the bot can do."
+	botShortDescription = "Save messages and files to Blinko. Send /list_commands for help."
+
+	configureBotRetryDelay = time.Second
+)
+
+// configureBot sets the profile texts of the bot shown by Telegram. The name
+// is only changed when BOT_NAME is set, to keep the one set with BotFather.
+func (s *Service) configureBot(ctx context.Context) {
+	retryOnce(ctx, "set bot description", func() error {
+		_, err := s.currentBot().SetMyDescription(ctx, &bot.SetMyDescriptionParams{Description: botDescription})
+		return err
+	})
+	retryOnce(ctx, "set bot short description", func() error {
+		_, err := s.currentBot().SetMyShortDescription(ctx, &bot.SetMyShortDescriptionParams{ShortDescription: botShortDescription})
+		return err
+	})
+	if s.config.BotName != "" {
+		retryOnce(ctx, "set bot name", func() error {
+			_, err := s.currentBot().SetMyName(ctx, &bot.SetMyNameParams{Name: s.config.BotName})
+			return err
+		})
+	}
+}
+
+// retryOnce calls fn again after a short delay if it fails, logging the
+// error if the retry fails too.
+func retryOnce(ctx context.Context, action string, fn func() error) {
+	err := fn()
+	if err == nil {
+		return
+	}
+	slog.Warn("failed to "+action+", retrying", slog.Any("err", err))
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(configureBotRetryDelay):
+	}
+	if err := fn(); err != nil {
+		slog.Error("failed to "+action, slog.Any("err", err))
+	}
+}
+
+// commandScope returns the scope the bot commands are registered for.
+func (s *Service) commandScope() models.BotCommandScope {
+	if s.config.BotCommandScope == "private" {
+		return &models.BotCommandScopeAllPrivateChats{}
+	}
+	return &models.BotCommandScopeDefault{}
 }
 
-func (s *Service) createMemo(content string) (BlinkoItem, error) {
+func (s *Service) createMemo(client *BlinkoClient, content string, tags []string, notebookID int) (BlinkoItem, error) {
 	item := BlinkoItem{
-		Content: content,
-		Type: 		 0,
+		Content:    content,
+		Type:       typePtr(NoteTypeFlash),
+		Tags:       tags,
+		NotebookID: notebookID,
 	}
-	memo, err := s.client.UpsertBlinko(item)
+	memo, err := client.UpsertBlinko(item)
 	if err != nil {
 		slog.Error("failed to create memo", slog.Any("err", err))
 		return BlinkoItem{}, err
 	}
+	if len(memo.Tags) == 0 {
+		memo.Tags = tags
+	}
 	return memo, nil
 }
 
-func (s *Service) handleMemoCreation(m *models.Update, content string) (BlinkoItem, error) {
+// rememberMemo records the memo the user created in the store.
+func (s *Service) rememberMemo(userID int64, memo BlinkoItem) {
+	createdAt := time.Now()
+	if memo.CreatedAt != nil {
+		createdAt = memo.CreatedAt.Local()
+	}
+	s.store.IncrementNotesCreated(userID)
+	s.store.RecordNoteCreation(userID, createdAt)
+	s.store.SetNoteOwner(memo.ID, userID)
+	s.store.SetLastNoteID(userID, memo.ID)
+}
+
+func (s *Service) handleMemoCreation(ctx context.Context, client *BlinkoClient, m *models.Update, content string, tags []string) (memo BlinkoItem, err error) {
+	ctx, span := tracer.Start(ctx, "blinkogram.Service.handleMemoCreation")
+	defer func() {
+		if err != nil {
+			recordError(span, err)
+		}
+		span.End()
+	}()
+
+	// Deferred first so that the admin is alerted after s.mutex is released.
+	var burst int
+	defer func() {
+		s.reportBurst(m.Message.From.ID, burst)
+	}()
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	var memo BlinkoItem
-	var err error
+	notebookID := s.notebookID(m.Message.From.ID)
 
 	if m.Message.MediaGroupID != "" {
 
-		// Try to get from cache first
-		if cacheMemo, ok := s.cache.get(m.Message.MediaGroupID); ok {
-			return cacheMemo.(BlinkoItem), nil
+		// Reuse the memo created for the first message of the group
+		if memoID, ok := s.store.GetMediaGroupMemoID(m.Message.MediaGroupID); ok {
+			memo, err = client.GetNoteDetail(memoID)
+			if err != nil {
+				return BlinkoItem{}, errors.Wrap(err, "failed to get memo for media group")
+			}
+			s.countMessage(m.Message.From.ID)
+			return memo, nil
 		}
 
-		// Create new memo if not in cache
-		memo, err = s.createMemo(content)
+		// Create new memo if not in store
+		memo, err = s.createMemo(client, content, tags, notebookID)
 		if err != nil {
 			return BlinkoItem{}, errors.Wrap(err, "failed to create memo for media group")
 		}
+		burst = s.recordMemoCreation(m.Message.From.ID)
+		s.rememberMemo(m.Message.From.ID, memo)
 
-		// Cache the memo with media group ID
-		s.cache.set(m.Message.MediaGroupID, memo, 24*time.Hour)
+		// Remember the memo with media group ID
+		s.store.SetMediaGroupMemoID(m.Message.MediaGroupID, memo.ID, s.config.MediaGroupCacheTTL)
 	} else {
 		// Handle single message
-		memo, err = s.createMemo(content)
+		memo, err = s.createMemo(client, content, tags, notebookID)
 		if err != nil {
 			return BlinkoItem{}, errors.Wrap(err, "failed to create memo for single message")
 		}
+		burst = s.recordMemoCreation(m.Message.From.ID)
+		s.rememberMemo(m.Message.From.ID, memo)
 	}
+	s.countMessage(m.Message.From.ID)
 
 	return memo, nil
 }
 
 func (s *Service) handler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	ctx, span := tracer.Start(withCorrelationID(ctx, newCorrelationID()), "blinkogram.Service.handler")
+	defer span.End()
 	if m.Message == nil {
 		slog.Error("memo message is nil")
 		return
 	}
 	message := m.Message
+	if !s.topicAllowed(message) {
+		slog.Debug("ignoring message from topic", slog.Int64("chatID", message.Chat.ID), slog.Int("topicID", message.MessageThreadID))
+		return
+	}
+	message.Text = s.normalizeCommand(message.Text)
+	message.Caption = s.normalizeCommand(message.Caption)
 	if strings.HasPrefix(message.Text, "/start ") {
 		s.startHandler(ctx, b, m)
 		return
-	} else if strings.HasPrefix(message.Text, "/search ") {
+	} else if isCommand(message.Text, "search_in") {
+		s.searchInHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "batch_search") {
+		s.batchSearchHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "search") {
 		s.searchHandler(ctx, b, m)
 		return
+	} else if message.Text == "/logout" {
+		s.logoutHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "language") {
+		s.languageHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "daily_digest") {
+		s.dailyDigestHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "attach") {
+		s.attachHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "import") || isCommand(message.Caption, "import") {
+		s.importHandler(ctx, b, m)
+		return
+	} else if message.Text == "/duplicate_check" {
+		s.duplicateCheckHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "note") {
+		s.noteHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "open") {
+		s.openHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "length") {
+		s.lengthHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "word_count") {
+		s.wordCountHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "note_size") {
+		s.noteSizeHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "mark_read") {
+		s.markReadHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "mark_unread") {
+		s.markUnreadHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "unread") {
+		s.unreadHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "format") {
+		s.formatHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "search_replace") {
+		s.searchReplaceHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "mirror") {
+		s.mirrorHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "merge") {
+		s.mergeHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "convert") {
+		s.convertHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "pin_toggle") {
+		s.pinToggleHandler(ctx, b, m)
+		return
+	} else if message.Text == "/pin_all" {
+		s.pinAllHandler(ctx, b, m, true)
+		return
+	} else if message.Text == "/unpin_all" {
+		s.pinAllHandler(ctx, b, m, false)
+		return
+	} else if isCommand(message.Text, "transform") {
+		s.transformHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "whois") {
+		s.whoisHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "sandbox") {
+		s.sandboxHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "set_server") {
+		s.setServerHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "notebook") {
+		s.notebookHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "template") {
+		s.templateHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "subscribe") {
+		s.subscribeHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "unsubscribe") {
+		s.unsubscribeHandler(ctx, b, m)
+		return
+	} else if message.Text == "/tag_list" {
+		s.tagListHandler(ctx, b, m)
+		return
+	} else if message.Text == "/list_commands" {
+		s.listCommandsHandler(ctx, b, m)
+		return
+	} else if message.Text == "/notify_on_share" {
+		s.notifyOnShareHandler(ctx, b, m)
+		return
+	} else if message.Text == "/whoami" {
+		s.whoamiHandler(ctx, b, m)
+		return
+	} else if message.Text == "/streak" {
+		s.streakHandler(ctx, b, m)
+		return
+	} else if message.Text == "/me" {
+		s.meHandler(ctx, b, m)
+		return
+	} else if message.Text == "/cleanup_cache" {
+		s.cleanupCacheHandler(ctx, b, m)
+		return
+	} else if message.Text == "/stats_server" {
+		s.serverStatsHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "admin") {
+		s.adminHandler(ctx, b, m)
+		return
+	} else if isCommand(message.Text, "feedback") {
+		s.feedbackHandler(ctx, b, m)
+		return
 	}
 
 	userID := message.From.ID
 	if _, ok := s.store.GetUserAccessToken(userID); !ok {
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: message.Chat.ID,
-			Text:   "Please start the bot with /start <access_token>",
+			Text:   s.t(userID, "start_required"),
 		})
 		return
 	}
@@ -168,6 +707,27 @@ func (s *Service) handler(ctx context.Context, b *bot.Bot, m *models.Update) {
 	if len(contentEntities) > 0 {
 		content = formatContent(content, contentEntities)
 	}
+	if message.Game != nil {
+		content = formatGame(message.Game)
+	}
+	if message.Invoice != nil {
+		content = formatInvoice(message.Invoice)
+	}
+	if message.Giveaway != nil {
+		content = formatGiveaway(message.Giveaway)
+	}
+	if message.GiveawayWinners != nil {
+		content = formatGiveawayWinners(message.GiveawayWinners)
+	}
+	if message.UsersShared != nil {
+		content = formatUsersShared(message.UsersShared)
+	}
+	if message.ChatShared != nil {
+		content = formatChatShared(message.ChatShared)
+	}
+	if message.PaidMedia != nil && content == "" {
+		content = "Paid media"
+	}
 
 	// Add "forwarded from: originName" if message was forwarded
 	if message.ForwardOrigin != nil {
@@ -199,145 +759,595 @@ func (s *Service) handler(ctx context.Context, b *bot.Bot, m *models.Update) {
 			originUsername = channel.Username
 		}
 
-		if originUsername != "" {
-			content = fmt.Sprintf("Forwarded from [%s](https://t.me/%s)\n%s", originName, originUsername, content)
+		var forwarded strings.Builder
+		err := s.forwardTemplate.Execute(&forwarded, forwardAttribution{
+			Name:     originName,
+			Username: originUsername,
+			Content:  content,
+		})
+		if err != nil {
+			slog.Error("failed to execute forward template", slog.Any("err", err))
 		} else {
-			content = fmt.Sprintf("Forwarded from %s\n%s", originName, content)
+			content = forwarded.String()
 		}
 	}
 
-	hasResource := message.Document != nil || len(message.Photo) > 0 || message.Voice != nil || message.Video != nil
+	hasResource := message.Document != nil || len(message.Photo) > 0 || message.Voice != nil || message.Video != nil || message.PaidMedia != nil
 	if content == "" && !hasResource {
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: message.Chat.ID,
-			Text:   "Please input memo content",
+			Text:   s.t(userID, "input_content"),
 		})
 		return
 	}
 
-	accessToken, _ := s.store.GetUserAccessToken(userID)
-	s.client.UpdateToken(accessToken)
+	client := s.newUserClient(ctx, userID)
+
+	// Append to the original memo when replying to one of our confirmations
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == b.ID() {
+		if memoId, ok := s.replyMemoID(message.ReplyToMessage); ok {
+			s.appendToMemo(ctx, b, client, m, memoId, content)
+			return
+		}
+	}
+
+	if transform := s.userPreferences(userID).Transform; transform != nil {
+		content = transform(content)
+	}
+
+	s.saveMemo(ctx, b, client, m, content, extractHashtags(message))
+}
+
+// saveMemo creates the memo with the content of the message and confirms
+// it, unless the user is in sandbox mode or rate limited. The memo is queued
+// when Blinko is unavailable.
+func (s *Service) saveMemo(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, content string, tags []string) {
+	message := m.Message
+	userID := message.From.ID
+	if s.store.GetUserSandbox(userID) {
+		s.sendSandboxPreview(ctx, b, message, content)
+		return
+	}
+
+	if delay, ok := s.throttle(userID); !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   s.t(userID, "rate_limited", waitSeconds(delay)),
+		})
+		return
+	}
+
+	content = s.summarize(ctx, content)
 
 	var memo BlinkoItem
-	memo, err := s.handleMemoCreation(m, content)
+	memo, err := s.handleMemoCreation(ctx, client, m, content, tags)
 	if err != nil {
+		if isUnavailable(err) && s.enqueue(b, m, content, tags) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: message.Chat.ID,
+				Text:   s.t(userID, "content_queued"),
+			})
+			return
+		}
+		text := s.t(userID, "create_failed")
+		var blinkoErr *BlinkoError
+		if errors.As(err, &blinkoErr) && blinkoErr.IsUnauthorized() {
+			text = s.t(userID, "token_rejected")
+		}
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: message.Chat.ID,
-			Text:   "Failed to create memo",
+			Text:   text,
 		})
 		return
 	}
 
-	if message.Document != nil {
-		s.processFileMessage(ctx, b, m, message.Document.FileID, memo)
-	}
-	if message.Voice != nil {
-		s.processFileMessage(ctx, b, m, message.Voice.FileID, memo)
-	}
-	if message.Video != nil {
-		s.processFileMessage(ctx, b, m, message.Video.FileID, memo)
-	}
-	if len(message.Photo) > 0 {
-		photo := message.Photo[len(message.Photo)-1]
-		s.processFileMessage(ctx, b, m, photo.FileID, memo)
-	}
+	s.confirmMemo(ctx, b, client, m, memo)
+}
 
-	b.SendMessage(ctx, &bot.SendMessageParams{
+// confirmMemo uploads the resources of the message into the newly created
+// memo and replies with the confirmation.
+func (s *Service) confirmMemo(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, memo BlinkoItem) {
+	message := m.Message
+	s.processResources(ctx, b, client, m, memo)
+
+	confirmation, _ := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:              message.Chat.ID,
-		Text:                fmt.Sprintf("Content saved as Private with %d", memo.ID),
+		Text:                s.t(message.From.ID, "content_saved", memo.ID),
 		ParseMode:           models.ParseModeMarkdown,
 		DisableNotification: true,
 		ReplyParameters: &models.ReplyParameters{
 			MessageID: message.ID,
 		},
-		ReplyMarkup: s.keyboard(memo.ID),
+		ReplyMarkup: s.keyboard(message.From.ID, memo),
 	})
+	s.rememberConfirmation(confirmation, message.From.ID, memo.ID)
+	s.notifySubscribers(ctx, b, message.From.ID, memo)
 }
 
-func (s *Service) startHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
-	userID := m.Message.From.ID
-	accessToken := strings.TrimPrefix(m.Message.Text, "/start ")
-
-	s.client.UpdateToken(accessToken)
-	userInfo, err := s.client.GetUserDetail()
-
-	if err != nil {
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: m.Message.Chat.ID,
-			Text:   "Invalid access token",
-		})
-		return
+// topicAllowed reports whether the bot should handle a group message
+// according to ALLOWED_TOPIC_IDS. Private chats are always handled.
+func (s *Service) topicAllowed(message *models.Message) bool {
+	if len(s.config.AllowedTopicIDs) == 0 || message.Chat.Type == models.ChatTypePrivate {
+		return true
 	}
-
-	s.store.SetUserAccessToken(userID, accessToken)
-	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: m.Message.Chat.ID,
-		Text:   fmt.Sprintf("Hello %s!", userInfo.Nickname),
-	})
+	return slices.Contains(s.config.AllowedTopicIDs, message.MessageThreadID)
 }
 
-func (s *Service) keyboard(memoId int) *models.InlineKeyboardMarkup {
-	// add inline keyboard to edit memo's visibility or pinned status.
-	return &models.InlineKeyboardMarkup{
-		InlineKeyboard: [][]models.InlineKeyboardButton{
+// processResources uploads every file attached to the message into the memo.
+func (s *Service) processResources(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, memo BlinkoItem) {
+	message := m.Message
+	if message.Document != nil {
+		s.processFileMessage(ctx, b, client, m, message.Document.FileID, memo)
+	}
+	if message.Voice != nil {
+		s.processFileMessage(ctx, b, client, m, message.Voice.FileID, memo)
+	}
+	if message.Video != nil {
+		s.processFileMessage(ctx, b, client, m, message.Video.FileID, memo)
+	}
+	if len(message.Photo) > 0 {
+		photo := message.Photo[len(message.Photo)-1]
+		s.processFileMessage(ctx, b, client, m, photo.FileID, memo)
+	}
+	if message.Game != nil && message.Game.Animation != nil {
+		s.processFileMessage(ctx, b, client, m, message.Game.Animation.FileID, memo)
+	}
+	if message.PaidMedia != nil {
+		for _, media := range message.PaidMedia.PaidMedia {
+			switch {
+			case media.Photo != nil && len(media.Photo.Photo) > 0:
+				photo := media.Photo.Photo[len(media.Photo.Photo)-1]
+				s.processFileMessage(ctx, b, client, m, photo.FileID, memo)
+			case media.Video != nil:
+				s.processFileMessage(ctx, b, client, m, media.Video.Video.FileID, memo)
+			}
+		}
+	}
+}
+
+// appendToMemo appends the content and resources of the message to an existing memo.
+func (s *Service) appendToMemo(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, memoId int, content string) {
+	message := m.Message
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, message.Chat.ID, message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	if content != "" {
+		if memo.Content != "" {
+			content = memo.Content + "\n" + content
+		}
+		memo, err = client.UpdateNoteContent(memo.ID, content)
+		if err != nil {
+			s.sendError(b, message.Chat.ID, message.From.ID, errors.Wrapf(err, "failed to update memo %d", memoId))
+			return
+		}
+	}
+
+	s.processResources(ctx, b, client, m, memo)
+
+	confirmation, _ := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:              message.Chat.ID,
+		Text:                s.t(message.From.ID, "content_appended", memo.ID),
+		DisableNotification: true,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID: message.ID,
+		},
+		ReplyMarkup: s.keyboard(message.From.ID, memo),
+	})
+	s.rememberConfirmation(confirmation, message.From.ID, memo.ID)
+}
+
+// startHandler handles /start and its deep-link payloads:
+//
+//	/start note_<id>      shows the memo
+//	/start share_<id>     links to the shared memo
+//	/start token_<token>  signs in, like /start <access_token> [refresh_token]
+func (s *Service) startHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	payload := strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/start"))
+	if id, ok := strings.CutPrefix(payload, "note_"); ok {
+		memoId, err := strconv.Atoi(id)
+		if err != nil {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Invalid memo ID %q", id), err))
+			return
+		}
+		if client, ok := s.authorize(ctx, b, m); ok {
+			s.sendNote(ctx, b, client, m, memoId)
+		}
+		return
+	}
+	if shareID, ok := strings.CutPrefix(payload, "share_"); ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("%s/share/%s", strings.TrimSuffix(s.serverAddr(m.Message.From.ID), "/"), shareID),
+		})
+		return
+	}
+	s.login(ctx, b, m, strings.TrimPrefix(payload, "token_"))
+}
+
+// login stores the tokens of the user after checking them with Blinko.
+func (s *Service) login(ctx context.Context, b *bot.Bot, m *models.Update, tokens string) {
+	userID := m.Message.From.ID
+	// The refresh token is optional: <access_token> [refresh_token]
+	accessToken, refreshToken, _ := strings.Cut(tokens, " ")
+
+	serverAddr := s.serverAddr(userID)
+	client := NewBlinkoClient(serverAddr, s.clientOptionsFor(ctx)...)
+	client.UpdateToken(accessToken)
+	userInfo, err := client.GetUserDetail()
+
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Invalid access token",
+		})
+		return
+	}
+
+	s.store.SetUserTokens(userID, accessToken, strings.TrimSpace(refreshToken))
+	s.store.SetUserChatID(userID, m.Message.Chat.ID)
+	s.cache.set(userCacheKey(userID), userInfo, userCacheTTL)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Hello %s!", userInfo.Nickname),
+	})
+}
+
+// serverAddr returns the Blinko server of the user, SERVER_ADDR unless
+// changed with /set_server.
+func (s *Service) serverAddr(userID int64) string {
+	if serverURL := s.store.GetUserServerURL(userID); serverURL != "" {
+		return serverURL
+	}
+	return s.config.ServerAddr
+}
+
+// clientOptionsFor returns the client options for the requests of ctx.
+func (s *Service) clientOptionsFor(ctx context.Context) []BlinkoClientOption {
+	return append(slices.Clone(s.clientOptions), WithContext(ctx))
+}
+
+// newUserClient returns a client of its own for the user and the request.
+// Handlers run concurrently, so clients are never shared between updates.
+// Refreshed access tokens are saved to the store.
+func (s *Service) newUserClient(ctx context.Context, userID int64) *BlinkoClient {
+	serverAddr := s.serverAddr(userID)
+	client := NewBlinkoClient(serverAddr, s.clientOptionsFor(ctx)...)
+	accessToken, _ := s.store.GetUserAccessToken(userID)
+	refreshToken := s.store.GetUserRefreshToken(userID)
+	client.UpdateToken(accessToken)
+	client.UpdateRefreshToken(refreshToken, func(accessToken string) {
+		s.store.SetUserTokens(userID, accessToken, refreshToken)
+	})
+	return client
+}
+
+// authorize returns a client with the access token of the message sender,
+// asking the user to start the bot if none is stored.
+func (s *Service) authorize(ctx context.Context, b *bot.Bot, m *models.Update) (*BlinkoClient, bool) {
+	userID := m.Message.From.ID
+	_, ok := s.store.GetUserAccessToken(userID)
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   s.t(userID, "start_required"),
+		})
+		return nil, false
+	}
+	return s.newUserClient(ctx, userID), true
+}
+
+// memoIDArg parses the memo ID given as the first argument of the command.
+func memoIDArg(text, command string) (int, bool) {
+	args := strings.Fields(strings.TrimPrefix(text, "/"+command))
+	if len(args) == 0 {
+		return 0, false
+	}
+	memoId, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, false
+	}
+	return memoId, true
+}
+
+const truncatedNotice = "\n\nContent truncated..."
+
+func (s *Service) noteHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "note")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /note <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+	s.sendNote(ctx, b, client, m, memoId)
+}
+
+// sendNote shows the content of the memo with the memo buttons.
+func (s *Service) sendNote(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, memoId int) {
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	status := "unread"
+	if s.store.IsRead(m.Message.From.ID, memo.ID) {
+		status = "read"
+	}
+	text := []rune(fmt.Sprintf("[%d] (%s) %s", memo.ID, status, memo.Content))
+	if limit := s.config.MessageMaxLength; len(text) > limit {
+		text = append(text[:limit-len([]rune(truncatedNotice))], []rune(truncatedNotice)...)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      m.Message.Chat.ID,
+		Text:        string(text),
+		ReplyMarkup: s.keyboard(m.Message.From.ID, memo),
+	})
+}
+
+func (s *Service) lengthHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "length")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /length <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Memo #%d has %d characters and %d attachments.", memo.ID, len([]rune(memo.Content)), len(memo.Attachments)),
+	})
+}
+
+// listCommandsHandler lists the commands registered with Telegram, so the
+// list is always in sync with the command menu.
+func (s *Service) listCommandsHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	commands, err := b.GetMyCommands(ctx, &bot.GetMyCommandsParams{Scope: s.commandScope()})
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to get bot commands"))
+		return
+	}
+
+	if len(commands) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "No commands registered",
+		})
+		return
+	}
+
+	var sb strings.Builder
+	for _, command := range commands {
+		sb.WriteString(fmt.Sprintf("- /%s: %s\n", command.Command, command.Description))
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   sb.String(),
+	})
+}
+
+func (s *Service) setServerHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	serverURL := strings.TrimRight(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/set_server")), "/")
+	if u, err := url.Parse(serverURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /set_server <url>",
+		})
+		return
+	}
+	if _, ok := s.authorize(ctx, b, m); !ok {
+		return
+	}
+
+	// Check the token with a client for the new server before switching.
+	accessToken, _ := s.store.GetUserAccessToken(userID)
+	client := NewBlinkoClient(serverURL, s.clientOptionsFor(ctx)...)
+	client.UpdateToken(accessToken)
+	if _, err := client.GetUserDetail(); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Failed to sign in to the new server, kept the previous one", err))
+		return
+	}
+
+	s.store.SetUserServerURL(userID, serverURL)
+	s.invalidateUserCache(userID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   "Server updated.",
+	})
+}
+
+func (s *Service) languageHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	locale := strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/language"))
+
+	if !s.locales.has(locale) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   s.t(userID, "language_usage", strings.Join(s.locales.codes(), ", ")),
+		})
+		return
+	}
+
+	s.store.SetUserLocale(userID, locale)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   s.t(userID, "language_set", locale),
+	})
+}
+
+func (s *Service) logoutHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	s.store.DeleteUserAccessToken(userID)
+	s.invalidateUserCache(userID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   "Logged out. Start the bot with /start <access_token> to log in again.",
+	})
+}
+
+const userCacheTTL = 5 * time.Minute
+
+func userCacheKey(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// getUserDetail returns the Blinko user info of the user, fetching it from
+// the server only when it is not cached. The client must already carry the
+// user's access token.
+func (s *Service) getUserDetail(client *BlinkoClient, userID int64) (UserInfo, error) {
+	if userInfo, ok := s.cache.get(userCacheKey(userID)); ok {
+		return userInfo.(UserInfo), nil
+	}
+
+	userInfo, err := client.GetUserDetail()
+	if err != nil {
+		return UserInfo{}, err
+	}
+	s.cache.set(userCacheKey(userID), userInfo, userCacheTTL)
+	return userInfo, nil
+}
+
+// invalidateUserCache drops the cached user info of the user.
+func (s *Service) invalidateUserCache(userID int64) {
+	s.cache.delete(userCacheKey(userID))
+}
+
+// maxCallbackDataLength is the maximum size of callback data allowed by Telegram.
+const maxCallbackDataLength = 64
+
+func (s *Service) keyboard(userID int64, memo BlinkoItem) *models.InlineKeyboardMarkup {
+	memoId := memo.ID
+	// add inline keyboard to edit memo's visibility or pinned status.
+	keyboard := [][]models.InlineKeyboardButton{
+		{
 			{
-				{
-					Text:         "Public",
-					CallbackData: fmt.Sprintf("public %d", memoId),
-				},
-				{
-					Text:         "Private",
-					CallbackData: fmt.Sprintf("private %d", memoId),
-				},
-				{
-					Text:         "Pin",
-					CallbackData: fmt.Sprintf("pin %d", memoId),
-				},
+				Text:         "Public",
+				CallbackData: fmt.Sprintf("public %d", memoId),
+			},
+			{
+				Text:         "Private",
+				CallbackData: fmt.Sprintf("private %d", memoId),
+			},
+			{
+				Text:         "Pin",
+				CallbackData: fmt.Sprintf("pin %d", memoId),
 			},
 		},
 	}
+
+	// add a button per tag to list the memos with the same tag.
+	var tagButtons []models.InlineKeyboardButton
+	for _, tag := range memo.Tags {
+		callbackData := fmt.Sprintf("tag_filter %s", tag)
+		if strings.Contains(tag, " ") || len(callbackData) > maxCallbackDataLength {
+			continue
+		}
+		tagButtons = append(tagButtons, models.InlineKeyboardButton{
+			Text:         "#" + tag,
+			CallbackData: callbackData,
+		})
+	}
+	if len(tagButtons) > 0 {
+		keyboard = append(keyboard, tagButtons)
+	}
+
+	typeButton := models.InlineKeyboardButton{
+		Text:         "Note",
+		CallbackData: fmt.Sprintf("set_type %d", memoId),
+	}
+	if memo.NoteType() == NoteTypeNote {
+		typeButton.Text = "Flash"
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{typeButton})
+
+	if button, ok := s.openButton(userID, memo); ok {
+		keyboard = append(keyboard, []models.InlineKeyboardButton{button})
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
 }
 
 func (s *Service) callbackQueryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ctx = withCorrelationID(ctx, newCorrelationID())
 	callbackData := update.CallbackQuery.Data
 	userID := update.CallbackQuery.From.ID
-	accessToken, ok := s.store.GetUserAccessToken(userID)
-	if !ok {
+	if _, ok := s.store.GetUserAccessToken(userID); !ok {
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Please start the bot with /start <access_token>",
+			Text:            s.t(userID, "start_required"),
 			ShowAlert:       true,
 		})
 		return
 	}
-	s.client.UpdateToken(accessToken)
+	client := s.newUserClient(ctx, userID)
 
 	parts := strings.Split(callbackData, " ")
 	if len(parts) != 2 {
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Invalid command",
+			Text:            s.t(userID, "invalid_command"),
 			ShowAlert:       true,
 		})
 		return
 	}
 	slog.Info("parts", slog.Any("parts", parts))
 	action, memoName := parts[0], parts[1]
+	if action == "tag_filter" {
+		s.tagFilter(ctx, b, client, update, parts[1])
+		return
+	}
+	if action == "pin_all" || action == "unpin_all" {
+		s.pinAll(ctx, b, update, action == "pin_all")
+		return
+	}
 	memoId, err := strconv.Atoi(memoName)
 	if err != nil {
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Invalid memo ID",
+			Text:            s.t(userID, "invalid_memo_id"),
 			ShowAlert:       true,
 		})
 		return
 	}
 
-	memo, err := s.client.GetNoteDetail(memoId)
+	memo, err := client.GetNoteDetail(memoId)
 	if err != nil {
+		var text string
+		var blinkoErr *BlinkoError
+		isBlinkoErr := errors.As(err, &blinkoErr)
+		switch {
+		case isBlinkoErr && blinkoErr.IsNotFound():
+			text = s.t(userID, "memo_not_found", memoName)
+		case isBlinkoErr && blinkoErr.IsUnauthorized():
+			text = s.t(userID, "token_rejected")
+		default:
+			slog.Error("failed to get memo", slog.Int("memo_id", memoId), slog.Any("err", err))
+			text = s.t(userID, "internal_error")
+		}
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            fmt.Sprintf("Memo %s not found", memoName),
+			Text:            text,
 			ShowAlert:       true,
 		})
 		return
@@ -345,23 +1355,32 @@ func (s *Service) callbackQueryHandler(ctx context.Context, b *bot.Bot, update *
 
 	switch action {
 	case "public":
-		s.shareNote(ctx, memo.ID, true, b, update)
+		s.shareNote(ctx, client, memo, true, b, update)
 		return
 	case "private":
-		s.shareNote(ctx, memo.ID, false, b, update)
+		s.shareNote(ctx, client, memo, false, b, update)
 		return
 	case "pin":
 		memo.IsTop = !memo.IsTop
+	case "dedup":
+		s.deleteDuplicates(ctx, b, client, update, memo)
+		return
+	case "set_type":
+		s.toggleNoteType(ctx, b, client, update, memo)
+		return
+	case "format":
+		s.applyFormat(ctx, b, client, update, memo)
+		return
 	default:
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Unknown action",
+			Text:            s.t(userID, "unknown_action"),
 			ShowAlert:       true,
 		})
 		return
 	}
 
-	_, e := s.client.UpsertBlinko(BlinkoItem{
+	_, e := client.UpsertBlinko(BlinkoItem{
 		ID:      memo.ID,
 		Content: memo.Content,
 		IsTop:   memo.IsTop,
@@ -370,7 +1389,7 @@ func (s *Service) callbackQueryHandler(ctx context.Context, b *bot.Bot, update *
 		slog.Error("failed to update memo", slog.Any("err", e))
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Failed to update memo",
+			Text:            s.t(userID, "update_failed"),
 			ShowAlert:       true,
 		})
 		return
@@ -381,100 +1400,334 @@ func (s *Service) callbackQueryHandler(ctx context.Context, b *bot.Bot, update *
 	} else {
 		pinnedMarker = ""
 	}
-	status := "Public"
+	status := s.t(userID, "public")
 	if !memo.IsShare {
-		status = "Private"
+		status = s.t(userID, "private")
 	}
 	b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      update.CallbackQuery.Message.Message.Chat.ID,
 		MessageID:   update.CallbackQuery.Message.Message.ID,
-		Text:        fmt.Sprintf("Memo updated as %s with %d %s", status, memo.ID, pinnedMarker),
+		Text:        s.t(userID, "memo_updated_as", status, memo.ID, pinnedMarker),
 		ParseMode:   models.ParseModeMarkdown,
-		ReplyMarkup: s.keyboard(memo.ID),
+		ReplyMarkup: s.keyboard(userID, memo),
 	})
 
 	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 		CallbackQueryID: update.CallbackQuery.ID,
-		Text:            "Memo updated",
+		Text:            s.t(userID, "memo_updated"),
 	})
 }
 
-func (s *Service) shareNote(ctx context.Context, memoId int, share bool, b *bot.Bot, update *models.Update) bool {
-	e := s.client.ShareNote(memoId, share)
+// setNoteType changes the type of the memo, see NoteTypeFlash and
+// NoteTypeNote.
+func (s *Service) setNoteType(client *BlinkoClient, id, noteType int) error {
+	return errors.Wrapf(client.SetNoteType(id, noteType), "failed to set type of memo %d", id)
+}
+
+// noteTypes maps the type names accepted by /convert to the note types.
+var noteTypes = map[string]int{
+	"flash": NoteTypeFlash,
+	"note":  NoteTypeNote,
+}
+
+func (s *Service) convertHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	args := strings.Fields(strings.TrimPrefix(m.Message.Text, "/convert"))
+	var memoId, noteType int
+	var err error
+	ok := len(args) == 2
+	if ok {
+		memoId, err = strconv.Atoi(args[0])
+		noteType, ok = noteTypes[args[1]]
+	}
+	if !ok || err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /convert <id> flash|note",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	if err := s.setNoteType(client, memoId, noteType); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to convert memo %d", memoId), err))
+		return
+	}
+	text := fmt.Sprintf("Note #%d converted to flash note.", memoId)
+	if noteType == NoteTypeNote {
+		text = fmt.Sprintf("Note #%d converted to regular note.", memoId)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+// toggleNoteType switches the memo between a flash and a note.
+func (s *Service) toggleNoteType(ctx context.Context, b *bot.Bot, client *BlinkoClient, update *models.Update, memo BlinkoItem) {
+	userID := update.CallbackQuery.From.ID
+	noteType := NoteTypeNote
+	if memo.NoteType() == NoteTypeNote {
+		noteType = NoteTypeFlash
+	}
+	memo.Type = typePtr(noteType)
+	if err := s.setNoteType(client, memo.ID, noteType); err != nil {
+		slog.Error("failed to update memo type", slog.Any("err", err))
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            s.t(userID, "update_failed"),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      update.CallbackQuery.Message.Message.Chat.ID,
+		MessageID:   update.CallbackQuery.Message.Message.ID,
+		ReplyMarkup: s.keyboard(userID, memo),
+	})
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		Text:            s.t(userID, "memo_updated"),
+	})
+}
+
+func (s *Service) shareNote(ctx context.Context, client *BlinkoClient, memo BlinkoItem, share bool, b *bot.Bot, update *models.Update) bool {
+	userID := update.CallbackQuery.From.ID
+	e := client.ShareNote(memo.ID, share)
 	if e != nil {
 		slog.Error("failed to update memo", slog.Any("err", e))
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Failed to update memo",
+			Text:            s.t(userID, "update_failed"),
 			ShowAlert:       true,
 		})
 		return true
 	}
-	status := "Public"
+	status := s.t(userID, "public")
 	if !share {
-		status = "Private"
+		status = s.t(userID, "private")
 	}
+	memo.IsShare = share
 	b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      update.CallbackQuery.Message.Message.Chat.ID,
 		MessageID:   update.CallbackQuery.Message.Message.ID,
-		Text:        fmt.Sprintf("Memo updated as %s with %d", status, memoId),
+		Text:        s.t(userID, "memo_updated_as", status, memo.ID, ""),
 		ParseMode:   models.ParseModeMarkdown,
-		ReplyMarkup: s.keyboard(memoId),
+		ReplyMarkup: s.keyboard(userID, memo),
 	})
 	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 		CallbackQueryID: update.CallbackQuery.ID,
-		Text:            "Memo updated",
+		Text:            s.t(userID, "memo_updated"),
 	})
 	return false
 }
 
-func (s *Service) searchHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
-	userID := m.Message.From.ID
-	searchString := strings.TrimPrefix(m.Message.Text, "/search ")
+// tagFilter lists the memos with the tag of the pressed tag button.
+func (s *Service) tagFilter(ctx context.Context, b *bot.Bot, client *BlinkoClient, update *models.Update, tag string) {
+	results, err := client.SearchNotes(SearchParams{Tag: tag})
+	if err != nil {
+		slog.Error("failed to filter memos by tag", slog.String("tag", tag), slog.Any("err", err))
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            "Failed to filter memos",
+			ShowAlert:       true,
+		})
+		return
+	}
 
-	accessToken, _ := s.store.GetUserAccessToken(userID)
-	s.client.UpdateToken(accessToken)
+	s.sendMemoList(ctx, b, update.CallbackQuery.From.ID, update.CallbackQuery.Message.Message.Chat.ID, results.Items)
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+}
+
+const memoListInterval = 100 * time.Millisecond
+
+// sendMemoList sends the memos in as few messages as possible, or a notice
+// if there is none.
+func (s *Service) sendMemoList(ctx context.Context, b *bot.Bot, userID, chatID int64, memos []BlinkoItem) {
+	if len(memos) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "No memos found for the specified search criteria.",
+		})
+		return
+	}
 
-	results, err := s.client.GetNoteList(searchString)
+	// Send as few messages as possible to stay below the flood limits.
+	for i, batch := range batchMemos(memos, s.config.MessageMaxLength) {
+		if i > 0 {
+			time.Sleep(memoListInterval)
+		}
+		params := &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   batch.text,
+		}
+		// Every memo gets its button below the message it ends in.
+		var buttons [][]models.InlineKeyboardButton
+		for _, memo := range batch.memos {
+			if button, ok := s.openButton(userID, memo); ok {
+				button.Text += fmt.Sprintf(" #%d", memo.ID)
+				buttons = append(buttons, []models.InlineKeyboardButton{button})
+			}
+		}
+		if len(buttons) > 0 {
+			params.ReplyMarkup = &models.InlineKeyboardMarkup{InlineKeyboard: buttons}
+		}
+		b.SendMessage(ctx, params)
+	}
+}
+
+// openButton returns a button opening the memo in the Blinko web UI of the
+// user's server. Telegram rejects buttons with local URLs, so none is
+// returned for those servers.
+func (s *Service) openButton(userID int64, memo BlinkoItem) (models.InlineKeyboardButton, bool) {
+	serverAddr := s.serverAddr(userID)
+	if !isPublicURL(serverAddr) {
+		return models.InlineKeyboardButton{}, false
+	}
+
+	text := "Open in Blinko"
+	if !memo.IsShare {
+		text = "View (private)"
+	}
+	return models.InlineKeyboardButton{
+		Text: text,
+		URL:  noteWebURL(serverAddr, memo.ID),
+	}, true
+}
+
+// isPublicURL reports whether Telegram accepts the server address in URL
+// buttons.
+func isPublicURL(serverAddr string) bool {
+	u, err := url.Parse(serverAddr)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.Contains(u.Hostname(), ".")
+}
+
+// openHandler sends a button opening the memo in the Blinko web UI of the
+// user's server.
+func (s *Service) openHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "open")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /open <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
 
+	memo, err := client.GetNoteDetail(memoId)
 	if err != nil {
-		slog.Error("failed to search memos", slog.Any("err", err))
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
 		return
 	}
 
-	if len(results) == 0 {
+	serverAddr := s.serverAddr(m.Message.From.ID)
+	link := noteWebURL(serverAddr, memo.ID)
+	if !isPublicURL(serverAddr) {
+		// Telegram rejects buttons with local URLs, send the link as text.
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: m.Message.Chat.ID,
-			Text:   "No memos found for the specified search criteria.",
+			Text:   link,
 		})
-	} else {
-		for _, memo := range results {
-			tgMessage := fmt.Sprintf("[%d] %s", memo.ID, memo.Content)
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID: m.Message.Chat.ID,
-				Text:   tgMessage,
-			})
-		}
+		return
+	}
+
+	label := fmt.Sprintf("Open memo #%d", memo.ID)
+	if !memo.IsShare {
+		label += " (Private)"
 	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Memo #%d in Blinko:", memo.ID),
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text: label,
+						URL:  link,
+					},
+				},
+			},
+		},
+	})
+}
+
+// noteWebURL returns the URL of the note in the Blinko web UI.
+func noteWebURL(serverAddr string, id int) string {
+	return fmt.Sprintf("%s/note/%d", strings.TrimSuffix(serverAddr, "/"), id)
 }
 
-func (s *Service) processFileMessage(ctx context.Context, b *bot.Bot, m *models.Update, fileID string, memo BlinkoItem) {
+func (s *Service) processFileMessage(ctx context.Context, b *bot.Bot, client *BlinkoClient, m *models.Update, fileID string, memo BlinkoItem) {
+	ctx, span := tracer.Start(ctx, "blinkogram.Service.processFileMessage")
+	defer span.End()
+
 	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
 	if err != nil {
-		s.sendError(b, m.Message.Chat.ID, errors.Wrap(err, "failed to get file"))
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to get file"))
 		return
 	}
 
-	_, err = s.saveResourceFromFile(file, memo)
+	_, err = s.saveResourceFromFile(b, client, file, memo, s.uploadProgress(ctx, b, m.Message.Chat.ID))
 	if err != nil {
-		s.sendError(b, m.Message.Chat.ID, errors.Wrap(err, "failed to save resource"))
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to save resource"))
 		return
 	}
 }
 
-func (s *Service) saveResourceFromFile(file *models.File, memo BlinkoItem) (FileInfo, error) {
-	fileLink := s.bot.FileDownloadLink(file)
-	response, err := http.Get(fileLink)
+const chatActionInterval = 4 * time.Second
+
+// uploadProgress returns a ProgressFunc showing the "sending a file" chat
+// action while the upload is running. Telegram shows an action for five
+// seconds, so it is sent again at most every chatActionInterval.
+func (s *Service) uploadProgress(ctx context.Context, b *bot.Bot, chatID int64) ProgressFunc {
+	var lastAction time.Time
+	return func(bytesUploaded, totalBytes int64) {
+		if bytesUploaded >= totalBytes || time.Since(lastAction) < chatActionInterval {
+			return
+		}
+		lastAction = time.Now()
+		go b.SendChatAction(ctx, &bot.SendChatActionParams{
+			ChatID: chatID,
+			Action: models.ChatActionUploadDocument,
+		})
+	}
+}
+
+// saveResourceFromFile uploads the file and attaches it to the memo. Up to
+// UPLOAD_WORKERS files are uploaded at once, the memo is updated with one
+// file at a time.
+func (s *Service) saveResourceFromFile(b *bot.Bot, client *BlinkoClient, file *models.File, memo BlinkoItem, progress ProgressFunc) (FileInfo, error) {
+	s.fileWorkerPool <- struct{}{}
+	resource, err := s.uploadResource(b, client, file, progress)
+	<-s.fileWorkerPool
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	s.attachMutex.Lock()
+	defer s.attachMutex.Unlock()
+	client.UpsertBlinko(BlinkoItem{
+		ID:          memo.ID,
+		Content:     memo.Content,
+		Attachments: []FileInfo{resource},
+	})
+
+	return resource, nil
+}
+
+// uploadResource downloads the file with the bot that got it, as file IDs
+// are only valid for the bot which received them, and uploads it to Blinko.
+func (s *Service) uploadResource(b *bot.Bot, client *BlinkoClient, file *models.File, progress ProgressFunc) (FileInfo, error) {
+	fileLink := b.FileDownloadLink(file)
+	response, err := s.botHTTPClient.Get(fileLink)
 	if err != nil {
 		return FileInfo{}, errors.Wrap(err, "failed to download file")
 	}
@@ -485,70 +1738,350 @@ func (s *Service) saveResourceFromFile(file *models.File, memo BlinkoItem) (File
 		return FileInfo{}, errors.Wrap(err, "failed to read file")
 	}
 
-	resource, err := s.client.UploadFile(bytes, filepath.Base(file.FilePath))
-
+	resource, err := client.UploadFileWithProgress(bytes, filepath.Base(file.FilePath), progress)
 	if err != nil {
 		return FileInfo{}, errors.Wrap(err, "failed to create resource")
 	}
+	return resource, nil
+}
 
-	s.client.UpsertBlinko(BlinkoItem{
-		ID:          memo.ID,
-		Content:     memo.Content,
-		Attachments: []FileInfo{resource},
-	})
+// downloadFile downloads the Telegram file through the bot HTTP client. It
+// fails when the file is larger than maxSize bytes.
+func (s *Service) downloadFile(ctx context.Context, b *bot.Bot, file *models.File, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.botHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s", response.Status)
+	}
 
-	return resource, nil
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, NewUserError(fmt.Sprintf("The file exceeds the size limit of %d bytes", maxSize), nil)
+	}
+	return data, nil
 }
 
-func (s *Service) sendError(b *bot.Bot, chatID int64, err error) {
-	slog.Error("error", slog.Any("err", err))
+// sendError reports the error to the chat, in the language of the user.
+// Only the message of a UserError is shown, internal errors are logged and
+// replied with a generic message.
+func (s *Service) sendError(b *bot.Bot, chatID, userID int64, err error) {
+	text := s.t(userID, "internal_error")
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		slog.Warn("user error", slog.Any("err", err))
+		text = s.t(userID, "error", userErr.Message)
+	} else {
+		slog.Error("error", slog.Any("err", err))
+	}
+
 	b.SendMessage(context.Background(), &bot.SendMessageParams{
 		ChatID: chatID,
-		Text:   fmt.Sprintf("Error: %s", err.Error()),
+		Text:   text,
 	})
 }
 
-func formatContent(content string, contentEntities []models.MessageEntity) string {
+// extractHashtags returns the tags of the hashtag entities in the message,
+// without the leading "#".
+func extractHashtags(message *models.Message) []string {
+	content, entities := message.Text, message.Entities
+	if message.Caption != "" {
+		content, entities = message.Caption, message.CaptionEntities
+	}
 	contentRunes := utf16.Encode([]rune(content))
 
-	var sb strings.Builder
-	var prevEntity = models.MessageEntity{}
-	var entityContent string
-	re := regexp.MustCompile(`^(\s*)(.*)(\s*)$`)
+	var tags []string
+	for _, entity := range entities {
+		if entity.Type != models.MessageEntityTypeHashtag || entity.Offset+entity.Length > len(contentRunes) {
+			continue
+		}
+		tag := string(utf16.Decode(contentRunes[entity.Offset : entity.Offset+entity.Length]))
+		tags = append(tags, strings.TrimPrefix(tag, "#"))
+	}
+	return tags
+}
+
+// formatGame formats a game card as memo content. Telegram does not expose a
+// URL for games, so the game animation is attached as a resource instead.
+func formatGame(game *models.Game) string {
+	content := fmt.Sprintf("🎮 **%s**", game.Title)
+	if game.Description != "" {
+		content += "\n" + game.Description
+	}
+	return content
+}
+
+// zeroDecimalCurrencies are the currencies whose amounts Telegram sends
+// without minor units.
+var zeroDecimalCurrencies = map[string]bool{
+	"CLP": true,
+	"ISK": true,
+	"JPY": true,
+	"KRW": true,
+	"PYG": true,
+	"UGX": true,
+	"VND": true,
+	"XTR": true,
+}
+
+// formatInvoice formats an invoice as memo content, e.g.
+// "💳 **Coffee Subscription** - 4.99 USD".
+func formatInvoice(invoice *models.Invoice) string {
+	amount := strconv.Itoa(invoice.TotalAmount)
+	if !zeroDecimalCurrencies[invoice.Currency] {
+		amount = fmt.Sprintf("%d.%02d", invoice.TotalAmount/100, invoice.TotalAmount%100)
+	}
+	content := fmt.Sprintf("💳 **%s** - %s %s", invoice.Title, amount, invoice.Currency)
+	if invoice.Description != "" {
+		content += "\n" + invoice.Description
+	}
+	return content
+}
+
+// normalizeCommand strips the bot mention from a command, e.g.
+// "/search@blinkogrambot foo" becomes "/search foo", as sent in groups.
+func (s *Service) normalizeCommand(text string) string {
+	username := s.currentBotUsername()
+	if username == "" || !strings.HasPrefix(text, "/") {
+		return text
+	}
+	end := strings.IndexFunc(text, unicode.IsSpace)
+	if end < 0 {
+		end = len(text)
+	}
+	command, mention, ok := strings.Cut(text[:end], "@")
+	if !ok || !strings.EqualFold(mention, username) {
+		return text
+	}
+	return command + text[end:]
+}
+
+// giveawayPrize describes the prize of a giveaway.
+func giveawayPrize(description string, starCount, premiumMonths int) string {
+	switch {
+	case description != "":
+		return description
+	case starCount > 0:
+		return fmt.Sprintf("%d Telegram Stars", starCount)
+	case premiumMonths > 0:
+		return fmt.Sprintf("%d months of Telegram Premium", premiumMonths)
+	}
+	return ""
+}
+
+// formatGiveaway formats a giveaway as memo content.
+func formatGiveaway(giveaway *models.Giveaway) string {
+	content := "🎁 **Giveaway**"
+	if prize := giveawayPrize(giveaway.PrizeDescription, giveaway.PrizeStarCount, giveaway.PremiumSubscriptionMonthCount); prize != "" {
+		content += "\nPrize: " + prize
+	}
+	content += fmt.Sprintf("\nWinners: %d", giveaway.WinnerCount)
+	content += "\nEnds: " + time.Unix(int64(giveaway.WinnersSelectionDate), 0).UTC().Format("2006-01-02 15:04 UTC")
+	return content
+}
+
+// formatGiveawayWinners formats the winners of a giveaway as memo content.
+func formatGiveawayWinners(winners *models.GiveawayWinners) string {
+	content := "🏆 **Giveaway winners**"
+	if prize := giveawayPrize(winners.PrizeDescription, winners.PrizeStarCount, winners.PremiumSubscriptionMonthCount); prize != "" {
+		content += "\nPrize: " + prize
+	}
+
+	names := make([]string, 0, len(winners.Winners))
+	for _, user := range winners.Winners {
+		if user.Username != "" {
+			names = append(names, "@"+user.Username)
+		} else {
+			names = append(names, strings.TrimSpace(user.FirstName+" "+user.LastName))
+		}
+	}
+	if len(names) > 0 {
+		content += "\nWinners: " + strings.Join(names, ", ")
+	} else {
+		content += fmt.Sprintf("\nWinners: %d", winners.WinnerCount)
+	}
+	return content
+}
 
+// formatUsersShared formats the users shared with the bot as memo content,
+// one line per user. The username is only known if it was requested.
+func formatUsersShared(shared *models.UsersShared) string {
+	lines := make([]string, 0, len(shared.Users))
+	for _, user := range shared.Users {
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if user.Username != "" {
+			name = "@" + user.Username
+		}
+		if name == "" {
+			lines = append(lines, fmt.Sprintf("👤 Shared user: %d", user.UserID))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("👤 Shared user: %s (ID: %d)", name, user.UserID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatChatShared formats the chat shared with the bot as memo content.
+func formatChatShared(shared *models.ChatShared) string {
+	title := shared.Title
+	if title == "" && shared.Username != "" {
+		title = "@" + shared.Username
+	}
+	if title == "" {
+		return fmt.Sprintf("💬 Shared chat: %d", shared.ChatID)
+	}
+	return fmt.Sprintf("💬 Shared chat: %s (ID: %d)", title, shared.ChatID)
+}
+
+// isCommand reports whether text invokes the command, with or without arguments.
+func isCommand(text, command string) bool {
+	return text == "/"+command || strings.HasPrefix(text, "/"+command+" ")
+}
+
+// replyMemoID returns the memo of the bot confirmation the message replies
+// to, remembered by message ID or parsed from the confirmation text.
+func (s *Service) replyMemoID(reply *models.Message) (int, bool) {
+	if cached, ok := s.cache.get(confirmationCacheKey(reply.Chat.ID, reply.ID)); ok {
+		return cached.(confirmedMemo).memoID, true
+	}
+	return s.locales.parseMemoID(reply.Text)
+}
+
+// formatContent converts the supported message entities into Markdown.
+// Overlapping entities are split so that they nest, and formatting is
+// applied from the innermost entity outwards.
+func formatContent(content string, contentEntities []models.MessageEntity) string {
+	contentRunes := utf16.Encode([]rune(content))
+
+	var entities []models.MessageEntity
 	for _, entity := range contentEntities {
 		switch entity.Type {
 		case models.MessageEntityTypeURL:
 		case models.MessageEntityTypeTextLink:
 		case models.MessageEntityTypeBold:
 		case models.MessageEntityTypeItalic:
+		case models.MessageEntityTypeCustomEmoji:
 		default:
 			continue
 		}
+		if entity.Offset < 0 || entity.Length <= 0 || entity.Offset+entity.Length > len(contentRunes) {
+			continue
+		}
+		entities = append(entities, entity)
+	}
 
-		if entity.Offset >= prevEntity.Offset+prevEntity.Length {
-			sb.WriteString(entityContent)
-			sb.WriteString(string(utf16.Decode(contentRunes[prevEntity.Offset+prevEntity.Length : entity.Offset])))
-			entityContent = string(utf16.Decode(contentRunes[entity.Offset : entity.Offset+entity.Length]))
-			prevEntity = entity
-			if strings.TrimSpace(entityContent) == "" {
-				continue
+	tree := buildEntityTree(splitOverlappingEntities(entities))
+	return renderEntities(contentRunes, 0, len(contentRunes), tree)
+}
+
+type entityNode struct {
+	entity   models.MessageEntity
+	children []*entityNode
+}
+
+func entityEnd(entity models.MessageEntity) int {
+	return entity.Offset + entity.Length
+}
+
+// splitOverlappingEntities splits every entity that starts inside another
+// entity but ends after it, so that any two entities are either disjoint or
+// nested.
+func splitOverlappingEntities(entities []models.MessageEntity) []models.MessageEntity {
+	for split := true; split; {
+		split = false
+		for i := 0; i < len(entities) && !split; i++ {
+			for j := 0; j < len(entities) && !split; j++ {
+				outer, inner := entities[i], entities[j]
+				if outer.Offset < inner.Offset && inner.Offset < entityEnd(outer) && entityEnd(outer) < entityEnd(inner) {
+					head, tail := inner, inner
+					head.Length = entityEnd(outer) - inner.Offset
+					tail.Offset = entityEnd(outer)
+					tail.Length = entityEnd(inner) - entityEnd(outer)
+					entities[j] = head
+					entities = append(entities, tail)
+					split = true
+				}
 			}
 		}
+	}
+	return entities
+}
 
-		matches := re.FindStringSubmatch(entityContent)
-		switch entity.Type {
-		case models.MessageEntityTypeURL:
-			entityContent = fmt.Sprintf("%s[%s](%s)%s", matches[1], matches[2], matches[2], matches[3])
-		case models.MessageEntityTypeTextLink:
-			entityContent = fmt.Sprintf("%s[%s](%s)%s", matches[1], matches[2], entity.URL, matches[3])
-		case models.MessageEntityTypeBold:
-			entityContent = fmt.Sprintf("%s**%s**%s", matches[1], matches[2], matches[3])
-		case models.MessageEntityTypeItalic:
-			entityContent = fmt.Sprintf("%s*%s*%s", matches[1], matches[2], matches[3])
+// buildEntityTree arranges non-overlapping entities into a tree where every
+// entity is a child of the smallest entity containing it.
+func buildEntityTree(entities []models.MessageEntity) []*entityNode {
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Offset != entities[j].Offset {
+			return entities[i].Offset < entities[j].Offset
+		}
+		return entities[i].Length > entities[j].Length
+	})
+
+	var roots, stack []*entityNode
+	for _, entity := range entities {
+		node := &entityNode{entity: entity}
+		for len(stack) > 0 && entity.Offset >= entityEnd(stack[len(stack)-1].entity) {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
 		}
+		stack = append(stack, node)
+	}
+	return roots
+}
+
+var surroundingSpaceRegexp = regexp.MustCompile(`(?s)^(\s*)(.*?)(\s*)$`)
+
+func renderEntities(contentRunes []uint16, start, end int, nodes []*entityNode) string {
+	var sb strings.Builder
+	pos := start
+	for _, node := range nodes {
+		entity := node.entity
+		sb.WriteString(string(utf16.Decode(contentRunes[pos:entity.Offset])))
+		entityContent := renderEntities(contentRunes, entity.Offset, entityEnd(entity), node.children)
+		sb.WriteString(applyEntity(entity, entityContent, string(utf16.Decode(contentRunes[entity.Offset:entityEnd(entity)]))))
+		pos = entityEnd(entity)
 	}
-	sb.WriteString(entityContent)
-	sb.WriteString(string(utf16.Decode(contentRunes[prevEntity.Offset+prevEntity.Length:])))
+	sb.WriteString(string(utf16.Decode(contentRunes[pos:end])))
 	return sb.String()
 }
+
+// applyEntity wraps the rendered entity content in Markdown, keeping
+// surrounding whitespace outside of the markers. raw is the unformatted text
+// covered by the entity.
+func applyEntity(entity models.MessageEntity, entityContent, raw string) string {
+	if strings.TrimSpace(entityContent) == "" {
+		return entityContent
+	}
+
+	matches := surroundingSpaceRegexp.FindStringSubmatch(entityContent)
+	switch entity.Type {
+	case models.MessageEntityTypeURL:
+		return fmt.Sprintf("%s[%s](%s)%s", matches[1], matches[2], strings.TrimSpace(raw), matches[3])
+	case models.MessageEntityTypeTextLink:
+		return fmt.Sprintf("%s[%s](%s)%s", matches[1], matches[2], entity.URL, matches[3])
+	case models.MessageEntityTypeBold:
+		return fmt.Sprintf("%s**%s**%s", matches[1], matches[2], matches[3])
+	case models.MessageEntityTypeItalic:
+		return fmt.Sprintf("%s*%s*%s", matches[1], matches[2], matches[3])
+	case models.MessageEntityTypeCustomEmoji:
+		// Blinko cannot show custom emoji, and their images can only be
+		// fetched with the bot token. Keep a reference to the sticker ID so
+		// that it is not silently replaced by the plain fallback emoji.
+		return fmt.Sprintf("%s{emoji:%s}%s", matches[1], entity.CustomEmojiID, matches[3])
+	}
+	return entityContent
+}
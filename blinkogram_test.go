@@ -0,0 +1,61 @@
+package blinkogram
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestFormatContentOverlappingEntities(t *testing.T) {
+	bold := func(offset, length int) models.MessageEntity {
+		return models.MessageEntity{Type: models.MessageEntityTypeBold, Offset: offset, Length: length}
+	}
+	italic := func(offset, length int) models.MessageEntity {
+		return models.MessageEntity{Type: models.MessageEntityTypeItalic, Offset: offset, Length: length}
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		entities []models.MessageEntity
+		want     string
+	}{
+		{
+			name:     "italic nested in bold",
+			content:  "bold italic",
+			entities: []models.MessageEntity{bold(0, 11), italic(5, 6)},
+			want:     "**bold *italic***",
+		},
+		{
+			name:     "same range",
+			content:  "both",
+			entities: []models.MessageEntity{bold(0, 4), italic(0, 4)},
+			want:     "***both***",
+		},
+		{
+			name:     "italic starting inside bold",
+			content:  "one two three",
+			entities: []models.MessageEntity{bold(0, 7), italic(4, 9)},
+			want:     "**one *two*** *three*",
+		},
+		{
+			name:     "bold starting inside italic",
+			content:  "one two three",
+			entities: []models.MessageEntity{italic(0, 7), bold(4, 9)},
+			want:     "*one **two*** **three**",
+		},
+		{
+			name:     "offsets in UTF-16 code units",
+			content:  "😀 bold italic",
+			entities: []models.MessageEntity{bold(3, 11), italic(8, 6)},
+			want:     "😀 **bold *italic***",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatContent(tt.content, tt.entities); got != tt.want {
+				t.Errorf("formatContent(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/pkg/errors"
+)
+
+// broadcastInterval keeps broadcasts below the Telegram limit of 30
+// messages per second.
+const broadcastInterval = 50 * time.Millisecond
+
+// Broadcast sends the message to every registered user, in the chat they
+// started the bot in.
+// With dryRun, the recipients are only logged.
+func (s *Service) Broadcast(ctx context.Context, message string, dryRun bool) error {
+	userIDs, err := s.store.ListAllUsers()
+	if err != nil {
+		return errors.Wrap(err, "failed to list users")
+	}
+
+	failed := 0
+	for i, userID := range userIDs {
+		if dryRun {
+			slog.Info("would broadcast message", slog.Int64("userID", userID))
+			continue
+		}
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(broadcastInterval):
+			}
+		}
+		if _, err := s.currentBot().SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: s.store.GetUserChatID(userID),
+			Text:   message,
+		}); err != nil {
+			slog.Warn("failed to broadcast message", slog.Int64("userID", userID), slog.Any("err", err))
+			failed++
+		}
+	}
+	slog.Info("broadcast message", slog.Int("users", len(userIDs)), slog.Int("failed", failed), slog.Bool("dryRun", dryRun))
+
+	if failed > 0 {
+		return errors.Errorf("failed to send the message to %d of %d users", failed, len(userIDs))
+	}
+	return nil
+}
@@ -46,15 +46,26 @@ func (c *Cache) get(key string) (interface{}, bool) {
 	return item.Value, true
 }
 
-// deleteExpired deletes all expired key value pairs
-func (c *Cache) deleteExpired() {
+// delete removes a key from the cache
+func (c *Cache) delete(key string) {
 	c.Lock()
 	defer c.Unlock()
+	delete(c.items, key)
+}
+
+// deleteExpired deletes all expired key value pairs and returns how many
+// were deleted
+func (c *Cache) deleteExpired() int {
+	c.Lock()
+	defer c.Unlock()
+	count := 0
 	for k, v := range c.items {
 		if time.Now().After(v.Expiration) {
 			delete(c.items, k)
+			count++
 		}
 	}
+	return count
 }
 
 // startGC starts a goroutine to clean expired key value pairs
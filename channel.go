@@ -0,0 +1,109 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+// channelMediaGroupPrefix prefixes the media group IDs of channel posts in
+// the store.
+const channelMediaGroupPrefix = "channel:"
+
+func isChannelPost(update *models.Update) bool {
+	return update.ChannelPost != nil
+}
+
+// channelPostHandler saves the channel posts as memos of the service
+// account set by SERVICE_TOKEN, with a client of its own for the post.
+func (s *Service) channelPostHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ctx = withCorrelationID(ctx, newCorrelationID())
+	post := update.ChannelPost
+	if post.Chat.ID != s.config.ChannelID {
+		slog.Debug("ignoring post from other channel", slog.Int64("chatID", post.Chat.ID))
+		return
+	}
+
+	content := post.Text
+	contentEntities := post.Entities
+	if post.Caption != "" {
+		content = post.Caption
+		contentEntities = post.CaptionEntities
+	}
+	if len(contentEntities) > 0 {
+		content = formatContent(content, contentEntities)
+	}
+
+	var fileIDs []string
+	if post.Document != nil {
+		fileIDs = append(fileIDs, post.Document.FileID)
+	}
+	if post.Voice != nil {
+		fileIDs = append(fileIDs, post.Voice.FileID)
+	}
+	if post.Video != nil {
+		fileIDs = append(fileIDs, post.Video.FileID)
+	}
+	if len(post.Photo) > 0 {
+		fileIDs = append(fileIDs, post.Photo[len(post.Photo)-1].FileID)
+	}
+	if content == "" && len(fileIDs) == 0 {
+		return
+	}
+
+	client := NewBlinkoClient(s.config.ServerAddr, s.clientOptionsFor(ctx)...)
+	client.UpdateToken(s.config.ServiceToken)
+	memo, err := s.createChannelMemo(client, post, content)
+	if err != nil {
+		slog.Error("failed to save channel post", slog.Int64("chatID", post.Chat.ID), slog.Int("messageID", post.ID), slog.Any("err", err))
+		return
+	}
+
+	for _, fileID := range fileIDs {
+		file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+		if err != nil {
+			slog.Error("failed to get channel post file", slog.Int("messageID", post.ID), slog.Any("err", err))
+			continue
+		}
+		if _, err := s.saveResourceFromFile(b, client, file, memo, nil); err != nil {
+			slog.Error("failed to save channel post file", slog.Int("messageID", post.ID), slog.Any("err", err))
+		}
+	}
+}
+
+// createChannelMemo creates the memo for the post, reusing the memo of the
+// other posts of the same media group. s.mutex is only held here, not while
+// the files are transferred.
+func (s *Service) createChannelMemo(client *BlinkoClient, post *models.Message, content string) (BlinkoItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	groupID := channelMediaGroupPrefix + post.MediaGroupID
+	if post.MediaGroupID != "" {
+		if memoID, ok := s.store.GetMediaGroupMemoID(groupID); ok {
+			memo, err := client.GetNoteDetail(memoID)
+			if err != nil {
+				return BlinkoItem{}, errors.Wrap(err, "failed to get memo for media group")
+			}
+			return memo, nil
+		}
+	}
+
+	memo, err := client.UpsertBlinko(BlinkoItem{
+		Type:       typePtr(NoteTypeFlash),
+		Content:    content,
+		Tags:       extractHashtags(post),
+		NotebookID: s.config.DefaultNotebookID,
+	})
+	if err != nil {
+		return BlinkoItem{}, errors.Wrap(err, "failed to create memo")
+	}
+
+	if post.MediaGroupID != "" {
+		s.store.SetMediaGroupMemoID(groupID, memo.ID, s.config.MediaGroupCacheTTL)
+	}
+	return memo, nil
+}
@@ -2,23 +2,42 @@ package blinkogram
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
+	neturl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	apiPathNoteUpsert    = "/api/v1/note/upsert"
-	apiPathNoteDetail    = "/api/v1/note/detail"
-	apiPathFileUpload    = "/api/file/upload"
-	apiPathGetNoteList   = "/api/v1/note/list"
-	apiPathShareNote     = "/api/v1/note/share"
-	apiPathGetUserDetail = "/api/v1/user/detail"
+	apiPathNoteUpsert      = "/api/%s/note/upsert"
+	apiPathNoteDetail      = "/api/%s/note/detail"
+	apiPathFileUpload      = "/api/file/upload"
+	apiPathGetNoteList     = "/api/%s/note/list"
+	apiPathShareNote       = "/api/%s/note/share"
+	apiPathNoteBatchDelete = "/api/%s/note/batch-delete"
+	apiPathGetUserDetail   = "/api/%s/user/detail"
+	apiPathAuthRefresh     = "/api/%s/auth/refresh"
+	apiPathServerVersion   = "/api/%s/public/version"
+	apiPathServerStats     = "/api/%s/server/stats"
 )
 
+const defaultAPIVersion = "v1"
+
 type BlinkoError struct {
 	StatusCode int
 	Message    string
@@ -28,33 +47,196 @@ func (e *BlinkoError) Error() string {
 	return fmt.Sprintf("blinko error: %d %s", e.StatusCode, e.Message)
 }
 
+// IsNotFound reports whether the requested note or resource does not exist.
+func (e *BlinkoError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether the access token was rejected.
+func (e *BlinkoError) IsUnauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// FlexInt is an integer the server sends either as a JSON number or as a
+// numeric string.
+type FlexInt int64
+
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	var number int64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*i = FlexInt(number)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	if str == "" {
+		*i = 0
+		return nil
+	}
+	number, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", str)
+	}
+	*i = FlexInt(number)
+	return nil
+}
+
 type FileInfo struct {
-	FilePath string      `json:"path"`
-	FileName string      `json:"name"`
-	Size     interface{} `json:"size"`
-	Type     string      `json:"type"`
+	FilePath string  `json:"path"`
+	FileName string  `json:"name"`
+	Size     FlexInt `json:"size"`
+	Type     string  `json:"type"`
 }
 
 type FileUploadResponse struct {
-	FilePath string `json:"filePath"`
-	FileName string `json:"fileName"`
-	Size     int    `json:"size"`
-	Type     string `json:"type"`
+	FilePath string  `json:"filePath"`
+	FileName string  `json:"fileName"`
+	Size     FlexInt `json:"size"`
+	Type     string  `json:"type"`
 }
 
+// Note types of Blinko.
+const (
+	NoteTypeFlash = 0
+	NoteTypeNote  = 1
+)
+
+// BlinkoItem is a note. Type is a pointer so that partial upserts, e.g.
+// adding an attachment, leave the type of the note untouched instead of
+// turning notes into flashes; use NoteType to read it.
 type BlinkoItem struct {
 	ID          int        `json:"id,omitempty"`
-	Type        int        `json:"type"`
+	Type        *int       `json:"type,omitempty"`
 	Content     string     `json:"content"`
 	Attachments []FileInfo `json:"attachments,omitempty"`
 	IsTop       bool       `json:"isTop"`
 	IsShare     bool       `json:"isShare,omitempty"`
+	Tags        NoteTags   `json:"tags,omitempty"`
+	NotebookID  int        `json:"notebookId,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// NoteType returns the type of the note, NoteTypeFlash if unset.
+func (i BlinkoItem) NoteType() int {
+	if i.Type == nil {
+		return NoteTypeFlash
+	}
+	return *i.Type
+}
+
+// typePtr returns a BlinkoItem.Type set to noteType.
+func typePtr(noteType int) *int {
+	return &noteType
+}
+
+// NoteTags is a list of tag names. The server returns tags either as plain
+// strings or as tag relation objects, both are accepted.
+type NoteTags []string
+
+func (t *NoteTags) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	tags := make(NoteTags, 0, len(raw))
+	for _, item := range raw {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			tags = append(tags, name)
+			continue
+		}
+
+		var relation struct {
+			Name string `json:"name"`
+			Tag  struct {
+				Name string `json:"name"`
+			} `json:"tag"`
+		}
+		if err := json.Unmarshal(item, &relation); err != nil {
+			return err
+		}
+		if relation.Tag.Name != "" {
+			tags = append(tags, relation.Tag.Name)
+		} else if relation.Name != "" {
+			tags = append(tags, relation.Name)
+		}
+	}
+	*t = tags
+	return nil
+}
+
+type NoteListParams struct {
+	SearchText string
+	Page       int
+	PageSize   int
+	Tag        string
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+type NoteListResponse struct {
+	Items []BlinkoItem `json:"items"`
+	Total int          `json:"total"`
+}
+
+// UnmarshalJSON accepts both a paginated object and the bare array
+// returned by servers without pagination support.
+func (r *NoteListResponse) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []BlinkoItem
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return err
+		}
+		r.Items = items
+		r.Total = len(items)
+		return nil
+	}
+
+	type noteListResponse NoteListResponse
+	var tmp noteListResponse
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	*r = NoteListResponse(tmp)
+	return nil
 }
 
 type BlinkoClient struct {
 	baseURL    string
+	apiVersion string
 	token      string
+	ctx        context.Context
 	httpClient *http.Client
+	logger     *slog.Logger
+	retries    int
+	metrics    bool
+
+	// tokenMutex guards token and refreshToken, renewed while other
+	// requests of the client may be running. refreshMutex serializes the
+	// refreshes so that an expired token is only refreshed once.
+	tokenMutex     sync.Mutex
+	refreshMutex   sync.Mutex
+	refreshToken   string
+	onTokenRefresh func(accessToken string)
+
+	// DryRun logs the changes instead of sending them to the server, to test
+	// the bot without a Blinko server. Reads are still sent. Every method
+	// changing notes checks it, and doRequest refuses the other requests
+	// which are not idempotent.
+	DryRun bool
+}
+
+// ServerStats is the usage of the whole Blinko server.
+type ServerStats struct {
+	TotalNotes    int     `json:"totalNotes"`
+	TotalUsers    int     `json:"totalUsers"`
+	StorageUsed   FlexInt `json:"storageUsed"`
+	ServerVersion string  `json:"serverVersion"`
 }
 
 type UserInfo struct {
@@ -63,63 +245,324 @@ type UserInfo struct {
 	Nickname string `json:"nickName"`
 }
 
-func NewBlinkoClient(baseURL string) *BlinkoClient {
-	return &BlinkoClient{
-		baseURL: baseURL,
+// BlinkoClientOption configures a BlinkoClient.
+type BlinkoClientOption func(*BlinkoClient)
+
+// WithHTTPClient replaces the HTTP client used to reach the server.
+func WithHTTPClient(httpClient *http.Client) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of every request, 30 seconds by default.
+func WithTimeout(timeout time.Duration) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithRetries retries idempotent requests failing with a network error or a
+// 5xx status up to retries times. Requests are not retried by default.
+func WithRetries(retries int) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.retries = retries
+	}
+}
+
+// WithTLSConfig sets the TLS configuration, e.g. to trust the CA of a self
+// hosted server. The client gets a transport of its own, use WithHTTPClient
+// to share one between clients.
+func WithTLSConfig(tlsConfig *tls.Config) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+		transport = transport.Clone()
+		transport.TLSClientConfig = tlsConfig
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithAPIVersion sets the version in the API paths, e.g. "v1" for
+// /api/v1/note/upsert.
+func WithAPIVersion(version string) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.apiVersion = version
+	}
+}
+
+// WithLogger sets the logger of the client, slog.Default() by default.
+func WithLogger(logger *slog.Logger) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.logger = logger
+	}
+}
+
+// WithContext sets the context of the requests, context.Background() by
+// default. The correlation ID carried by ctx is sent as the X-Request-ID
+// header. Clients are built per update, so the context never changes.
+func WithContext(ctx context.Context) BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.ctx = ctx
+	}
+}
+
+// WithDryRun enables BlinkoClient.DryRun.
+func WithDryRun() BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		c.DryRun = true
+	}
+}
+
+func NewBlinkoClient(baseURL string, opts ...BlinkoClientOption) *BlinkoClient {
+	c := &BlinkoClient{
+		baseURL:    baseURL,
+		apiVersion: defaultAPIVersion,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		logger: slog.Default(),
+		ctx:    context.Background(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 func (c *BlinkoClient) UpdateToken(token string) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
 	c.token = token
 }
 
 func (c *BlinkoClient) HasToken() bool {
-	return c.token != ""
+	return c.accessToken() != ""
 }
 
+func (c *BlinkoClient) accessToken() string {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	return c.token
+}
+
+// UpdateRefreshToken sets the refresh token used to renew an expired access
+// token. onRefresh is called with the new access token after a refresh.
+func (c *BlinkoClient) UpdateRefreshToken(refreshToken string, onRefresh func(accessToken string)) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.refreshToken = refreshToken
+	c.onTokenRefresh = onRefresh
+}
+
+var errDryRun = errors.New("request not sent in dry run mode")
+
+// doRequest sends the request, recording it in the metrics if enabled.
 func (c *BlinkoClient) doRequest(req *http.Request) ([]byte, error) {
+	if c.DryRun && !isIdempotent(req) {
+		c.logger.Warn("dry run: refusing request", slog.String("method", req.Method), slog.String("url", req.URL.Path))
+		return nil, errDryRun
+	}
+	if !c.metrics {
+		return c.doRefreshingRequest(req)
+	}
+	start := time.Now()
+	body, err := c.doRefreshingRequest(req)
+	observeBlinkoRequest(req, start, err)
+	return body, err
+}
+
+// doRefreshingRequest sends the request. If the access token is rejected and
+// a refresh token is set, the access token is refreshed and the request
+// retried once.
+func (c *BlinkoClient) doRefreshingRequest(req *http.Request) ([]byte, error) {
+	sentToken := c.accessToken()
+	body, err := c.sendWithRetries(req)
+	blinkoErr, ok := err.(*BlinkoError)
+	if !ok || !blinkoErr.IsUnauthorized() {
+		return body, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return body, err
+	}
+	if refreshErr := c.refreshAccessToken(sentToken); refreshErr != nil {
+		if !errors.Is(refreshErr, errNoRefreshToken) {
+			c.logger.Warn("failed to refresh blinko access token", slog.Any("err", refreshErr))
+		}
+		return body, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return c.sendWithRetries(retry)
+}
+
+var errNoRefreshToken = errors.New("no refresh token")
+
+// refreshAccessToken refreshes the access token rejected by the server,
+// unless a concurrent request already replaced it.
+func (c *BlinkoClient) refreshAccessToken(rejectedToken string) error {
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+
+	c.tokenMutex.Lock()
+	token, refreshToken, onRefresh := c.token, c.refreshToken, c.onTokenRefresh
+	c.tokenMutex.Unlock()
+	if token != rejectedToken {
+		return nil
+	}
+	if refreshToken == "" {
+		return errNoRefreshToken
+	}
+
+	token, err := c.RefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	c.UpdateToken(token)
+	if onRefresh != nil {
+		onRefresh(token)
+	}
+	return nil
+}
+
+const retryBackoff = 500 * time.Millisecond
+
+// readOnlyKey marks the context of the POST requests which only read, e.g.
+// the note detail, so that they are retried like GET requests.
+type readOnlyKey struct{}
+
+func withReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// isIdempotent reports whether the request can be sent again without side
+// effects. Upserts are not, a retry after a timeout may create the note
+// twice.
+func isIdempotent(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	readOnly, _ := req.Context().Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+// sendWithRetries sends the request, retrying idempotent requests on
+// network errors and 5xx statuses as configured by WithRetries.
+func (c *BlinkoClient) sendWithRetries(req *http.Request) ([]byte, error) {
+	body, err := c.send(req)
+	if !isIdempotent(req) {
+		return body, err
+	}
+	for attempt := 1; attempt <= c.retries && isUnavailable(err); attempt++ {
+		if req.Body != nil && req.GetBody == nil {
+			break
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		c.logger.Debug("retrying blinko request", slog.Int("attempt", attempt), slog.String("url", req.URL.Path))
+		body, err = c.send(retry)
+	}
+	return body, err
+}
+
+// startSpan starts a span for the client method, child of ctx: the context
+// of the client, or the span context of the method calling it. Requests and
+// calls made with the returned context are its children.
+func (c *BlinkoClient) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "blinkogram.BlinkoClient."+method)
+}
+
+func (c *BlinkoClient) send(req *http.Request) ([]byte, error) {
+	ctx := req.Context()
+	requestID, ok := correlationIDFromContext(ctx)
+	if !ok {
+		requestID = newCorrelationID()
+		ctx = withCorrelationID(ctx, requestID)
+	}
+	req = req.WithContext(ctx)
+	logger := c.logger.With(slog.String("request_id", requestID), slog.String("method", req.Method), slog.String("url", req.URL.Path))
+
+	req.Header.Set(requestIDHeader, requestID)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 	req.Header.Set("Accept", "application/json")
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if token := c.accessToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 	if req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
+	logger.Debug("sending blinko request")
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		logger.Error("blinko request failed", slog.Any("err", err))
+		recordError(trace.SpanFromContext(ctx), err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		logger.Error("failed to read blinko response", slog.Any("err", err))
 		return nil, err
 	}
+	logger.Debug("received blinko response", slog.Int("status", resp.StatusCode))
 
 	// fmt.Printf("request [%s]: %s\n", req.URL, req.Body)
 	// fmt.Printf("response [%s]: %s\n\n", req.URL, string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, &BlinkoError{
+		logger.Warn("blinko request returned error status", slog.Int("status", resp.StatusCode))
+		err := &BlinkoError{
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
 		}
+		recordError(trace.SpanFromContext(ctx), err)
+		return nil, err
 	}
 
 	return body, nil
 }
 
 func (c *BlinkoClient) UpsertBlinko(item BlinkoItem) (BlinkoItem, error) {
+	return c.upsertBlinko(c.ctx, item)
+}
+
+func (c *BlinkoClient) upsertBlinko(ctx context.Context, item BlinkoItem) (BlinkoItem, error) {
+	ctx, span := c.startSpan(ctx, "UpsertBlinko")
+	defer span.End()
+
+	if c.DryRun {
+		c.logger.Info("dry run: upsert note", slog.Int("id", item.ID), slog.Int("type", item.NoteType()), slog.String("content", item.Content))
+		if item.ID == 0 {
+			item.ID = -1
+		}
+		return item, nil
+	}
+
 	jsonBody, err := json.Marshal(item)
 	if err != nil {
 		return BlinkoItem{}, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.baseURL+apiPathNoteUpsert, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+fmt.Sprintf(apiPathNoteUpsert, c.apiVersion), bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return BlinkoItem{}, err
 	}
@@ -137,7 +580,76 @@ func (c *BlinkoClient) UpsertBlinko(item BlinkoItem) (BlinkoItem, error) {
 	return result, nil
 }
 
+// UpdateNoteContent replaces the content of an existing note, leaving other fields untouched.
+func (c *BlinkoClient) UpdateNoteContent(id int, content string) (BlinkoItem, error) {
+	ctx, span := c.startSpan(c.ctx, "UpdateNoteContent")
+	defer span.End()
+
+	if c.DryRun {
+		c.logger.Info("dry run: update note content", slog.Int("id", id), slog.String("content", content))
+		return BlinkoItem{ID: id, Content: content}, nil
+	}
+
+	body := map[string]interface{}{
+		"id":      id,
+		"content": content,
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return BlinkoItem{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+fmt.Sprintf(apiPathNoteUpsert, c.apiVersion), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return BlinkoItem{}, err
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return BlinkoItem{}, err
+	}
+
+	var result BlinkoItem
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return BlinkoItem{}, err
+	}
+
+	return result, nil
+}
+
+// ProgressFunc is called while a file is uploaded with the number of bytes
+// of the request body sent so far.
+type ProgressFunc func(bytesUploaded, totalBytes int64)
+
+// progressWriter reports the bytes read through an io.TeeReader.
+type progressWriter struct {
+	uploaded int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.uploaded += int64(len(p))
+	w.progress(w.uploaded, w.total)
+	return len(p), nil
+}
+
 func (c *BlinkoClient) UploadFile(fileBytes []byte, filename string) (FileInfo, error) {
+	return c.UploadFileWithProgress(fileBytes, filename, nil)
+}
+
+// UploadFileWithProgress uploads the file like UploadFile, calling progress
+// as the request body is sent.
+func (c *BlinkoClient) UploadFileWithProgress(fileBytes []byte, filename string, progress ProgressFunc) (FileInfo, error) {
+	ctx, span := c.startSpan(c.ctx, "UploadFile")
+	defer span.End()
+
+	if c.DryRun {
+		c.logger.Info("dry run: upload file", slog.String("filename", filename), slog.Int("size", len(fileBytes)))
+		return FileInfo{FileName: filename, Size: FlexInt(len(fileBytes))}, nil
+	}
+
 	url := c.baseURL + apiPathFileUpload
 
 	body := &bytes.Buffer{}
@@ -149,10 +661,18 @@ func (c *BlinkoClient) UploadFile(fileBytes []byte, filename string) (FileInfo,
 	part.Write(fileBytes)
 	writer.Close()
 
-	req, err := http.NewRequest(http.MethodPost, url, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return FileInfo{}, err
 	}
+	if progress != nil {
+		data := body.Bytes()
+		req.GetBody = func() (io.ReadCloser, error) {
+			writer := &progressWriter{total: int64(len(data)), progress: progress}
+			return io.NopCloser(io.TeeReader(bytes.NewReader(data), writer)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
@@ -179,7 +699,14 @@ func (c *BlinkoClient) UploadFile(fileBytes []byte, filename string) (FileInfo,
 }
 
 func (c *BlinkoClient) GetNoteDetail(id int) (BlinkoItem, error) {
-	url := c.baseURL + apiPathNoteDetail
+	return c.getNoteDetail(c.ctx, id)
+}
+
+func (c *BlinkoClient) getNoteDetail(ctx context.Context, id int) (BlinkoItem, error) {
+	ctx, span := c.startSpan(ctx, "GetNoteDetail")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathNoteDetail, c.apiVersion)
 
 	body := map[string]interface{}{
 		"id": id,
@@ -190,7 +717,7 @@ func (c *BlinkoClient) GetNoteDetail(id int) (BlinkoItem, error) {
 		return BlinkoItem{}, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(withReadOnly(ctx), http.MethodPost, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return BlinkoItem{}, err
 	}
@@ -208,39 +735,209 @@ func (c *BlinkoClient) GetNoteDetail(id int) (BlinkoItem, error) {
 	return blinkoItem, nil
 }
 
-func (c *BlinkoClient) GetNoteList(searchText string) ([]BlinkoItem, error) {
-	url := c.baseURL + apiPathGetNoteList
+// SearchParams filters and orders the notes returned by SearchNotes. Zero
+// values are not sent. SortBy is one of created, updated or id and SortDir
+// is asc or desc.
+type SearchParams struct {
+	Query    string
+	Tag      string
+	SortBy   string
+	SortDir  string
+	Page     int
+	PageSize int
+	MinID    int
+	MaxID    int
+	Since    time.Time
+	Before   time.Time
+}
+
+// SearchResult is one page of notes. Pages is only known when the server
+// reports the total and a page size was requested.
+type SearchResult struct {
+	Items []BlinkoItem
+	Total int
+	Page  int
+	Pages int
+}
+
+// SearchNotes returns the page of notes matching the params. The server
+// does not support filtering by ID or sorting by field, so MinID, MaxID and
+// SortBy are applied to the returned page.
+func (c *BlinkoClient) SearchNotes(params SearchParams) (SearchResult, error) {
+	return c.searchNotes(c.ctx, params)
+}
+
+func (c *BlinkoClient) searchNotes(ctx context.Context, params SearchParams) (SearchResult, error) {
+	ctx, span := c.startSpan(ctx, "SearchNotes")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathGetNoteList, c.apiVersion)
 
 	body := map[string]interface{}{
-		"searchText": searchText,
+		"searchText": params.Query,
+	}
+	if params.Page > 0 {
+		body["page"] = params.Page
+	}
+	if params.PageSize > 0 {
+		body["pageSize"] = params.PageSize
+	}
+	if params.Tag != "" {
+		body["tag"] = params.Tag
+	}
+	if !params.Since.IsZero() {
+		body["startDate"] = params.Since
+	}
+	if !params.Before.IsZero() {
+		body["endDate"] = params.Before
+	}
+	if params.SortDir != "" {
+		body["orderBy"] = params.SortDir
 	}
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return nil, err
+		return SearchResult{}, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(withReadOnly(ctx), http.MethodPost, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, err
+		return SearchResult{}, err
 	}
 
 	resp, err := c.doRequest(req)
+	var blinkoErr *BlinkoError
+	if errors.As(err, &blinkoErr) && blinkoErr.StatusCode == http.StatusMethodNotAllowed {
+		// Some proxies only let GET through to the list endpoint.
+		resp, err = c.getNoteList(ctx, url, body)
+	}
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	var list NoteListResponse
+	err = json.Unmarshal(resp, &list)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	items := list.Items
+	if params.MinID > 0 || params.MaxID > 0 {
+		items = slices.DeleteFunc(items, func(item BlinkoItem) bool {
+			return (params.MinID > 0 && item.ID < params.MinID) || (params.MaxID > 0 && item.ID > params.MaxID)
+		})
+	}
+	sortMemos(items, memoSort{field: params.SortBy, desc: params.SortDir == "desc"})
+
+	result := SearchResult{
+		Items: items,
+		Total: list.Total,
+		Page:  max(params.Page, 1),
+	}
+	if params.PageSize > 0 && list.Total > 0 {
+		result.Pages = (list.Total + params.PageSize - 1) / params.PageSize
+	}
+	return result, nil
+}
+
+// getNoteList sends the note list request as GET, with the body fields as
+// query parameters.
+func (c *BlinkoClient) getNoteList(ctx context.Context, endpoint string, body map[string]interface{}) ([]byte, error) {
+	query := neturl.Values{}
+	for key, value := range body {
+		if t, ok := value.(time.Time); ok {
+			query.Set(key, t.Format(time.RFC3339))
+			continue
+		}
+		query.Set(key, fmt.Sprint(value))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
+	return c.doRequest(req)
+}
+
+// GetNoteList returns the page of notes matching the params.
+//
+// Deprecated: use SearchNotes.
+func (c *BlinkoClient) GetNoteList(params NoteListParams) (NoteListResponse, error) {
+	result, err := c.SearchNotes(SearchParams{
+		Query:    params.SearchText,
+		Tag:      params.Tag,
+		Page:     params.Page,
+		PageSize: params.PageSize,
+		Since:    params.StartDate,
+		Before:   params.EndDate,
+	})
+	if err != nil {
+		return NoteListResponse{}, err
+	}
+	return NoteListResponse{Items: result.Items, Total: result.Total}, nil
+}
+
+// GetNoteListByDate returns the notes created on the day of date, in date's location.
+func (c *BlinkoClient) GetNoteListByDate(date time.Time) ([]BlinkoItem, error) {
+	ctx, span := c.startSpan(c.ctx, "GetNoteListByDate")
+	defer span.End()
 
-	var blinkoItems []BlinkoItem
-	err = json.Unmarshal(resp, &blinkoItems)
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	result, err := c.searchNotes(ctx, SearchParams{
+		Since:  start,
+		Before: start.AddDate(0, 0, 1),
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result.Items, nil
+}
+
+// allNotesPageSize is the page size used to walk through all notes.
+const allNotesPageSize = 100
+
+// GetAllNotes returns every note of the user by walking through all pages.
+func (c *BlinkoClient) GetAllNotes() ([]BlinkoItem, error) {
+	ctx, span := c.startSpan(c.ctx, "GetAllNotes")
+	defer span.End()
 
-	return blinkoItems, nil
+	return c.listAllNotes(ctx, SearchParams{})
+}
+
+// SearchAllNotes returns every note matching the search text by walking
+// through all pages.
+func (c *BlinkoClient) SearchAllNotes(searchText string) ([]BlinkoItem, error) {
+	ctx, span := c.startSpan(c.ctx, "SearchAllNotes")
+	defer span.End()
+
+	return c.listAllNotes(ctx, SearchParams{Query: searchText})
+}
+
+func (c *BlinkoClient) listAllNotes(ctx context.Context, params SearchParams) ([]BlinkoItem, error) {
+	var items []BlinkoItem
+	params.PageSize = allNotesPageSize
+	for params.Page = 1; ; params.Page++ {
+		result, err := c.searchNotes(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if len(result.Items) < allNotesPageSize || (result.Total > 0 && len(items) >= result.Total) {
+			return items, nil
+		}
+	}
 }
 
 func (c *BlinkoClient) ShareNote(memoID int, isShare bool) error {
-	url := c.baseURL + apiPathShareNote
+	ctx, span := c.startSpan(c.ctx, "ShareNote")
+	defer span.End()
+
+	if c.DryRun {
+		c.logger.Info("dry run: share note", slog.Int("id", memoID), slog.Bool("share", isShare))
+		return nil
+	}
+
+	url := c.baseURL + fmt.Sprintf(apiPathShareNote, c.apiVersion)
 
 	body := map[string]interface{}{
 		"id":       memoID,
@@ -252,7 +949,7 @@ func (c *BlinkoClient) ShareNote(memoID int, isShare bool) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return err
 	}
@@ -265,10 +962,133 @@ func (c *BlinkoClient) ShareNote(memoID int, isShare bool) error {
 	return nil
 }
 
+func (c *BlinkoClient) DeleteNote(id int) error {
+	ctx, span := c.startSpan(c.ctx, "DeleteNote")
+	defer span.End()
+
+	return c.bulkDeleteNotes(ctx, []int{id})
+}
+
+// SetNoteType changes the type of the note, keeping everything else.
+func (c *BlinkoClient) SetNoteType(id, noteType int) error {
+	ctx, span := c.startSpan(c.ctx, "SetNoteType")
+	defer span.End()
+
+	note, err := c.getNoteDetail(ctx, id)
+	if err != nil {
+		return err
+	}
+	note.Type = typePtr(noteType)
+	_, err = c.upsertBlinko(ctx, note)
+	return err
+}
+
+// TogglePin flips the pinned status of the note and returns the updated
+// note. Blinko has no endpoint to toggle it atomically, so the note is read
+// first and callers must serialize concurrent toggles of the same note.
+func (c *BlinkoClient) TogglePin(id int) (BlinkoItem, error) {
+	ctx, span := c.startSpan(c.ctx, "TogglePin")
+	defer span.End()
+
+	note, err := c.getNoteDetail(ctx, id)
+	if err != nil {
+		return BlinkoItem{}, err
+	}
+	note.IsTop = !note.IsTop
+	if _, err := c.upsertBlinko(ctx, BlinkoItem{
+		ID:      note.ID,
+		Content: note.Content,
+		IsTop:   note.IsTop,
+	}); err != nil {
+		return BlinkoItem{}, err
+	}
+	return note, nil
+}
+
+// BulkDeleteNotes deletes the notes in a single request.
+func (c *BlinkoClient) BulkDeleteNotes(ids []int) error {
+	return c.bulkDeleteNotes(c.ctx, ids)
+}
+
+func (c *BlinkoClient) bulkDeleteNotes(ctx context.Context, ids []int) error {
+	ctx, span := c.startSpan(ctx, "BulkDeleteNotes")
+	defer span.End()
+
+	if c.DryRun {
+		c.logger.Info("dry run: delete notes", slog.Any("ids", ids))
+		return nil
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	url := c.baseURL + fmt.Sprintf(apiPathNoteBatchDelete, c.apiVersion)
+
+	body := map[string]interface{}{
+		"ids": ids,
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(req)
+	return err
+}
+
+// RefreshToken exchanges the refresh token for a new access token.
+func (c *BlinkoClient) RefreshToken(refreshToken string) (string, error) {
+	ctx, span := c.startSpan(c.ctx, "RefreshToken")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathAuthRefresh, c.apiVersion)
+
+	body := map[string]interface{}{
+		"refreshToken": refreshToken,
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+
+	// Send without doRequest to never refresh recursively
+	resp, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", err
+	}
+	if result.Token == "" {
+		return "", fmt.Errorf("empty token in refresh response")
+	}
+	return result.Token, nil
+}
+
 // 获取用户信息
 func (c *BlinkoClient) GetUserDetail() (UserInfo, error) {
-	url := c.baseURL + apiPathGetUserDetail
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	ctx, span := c.startSpan(c.ctx, "GetUserDetail")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathGetUserDetail, c.apiVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return UserInfo{}, err
 	}
@@ -283,3 +1103,68 @@ func (c *BlinkoClient) GetUserDetail() (UserInfo, error) {
 	}
 	return userDetail, nil
 }
+
+// GetServerVersion returns the version of the Blinko server, e.g. "1.2.3".
+func (c *BlinkoClient) GetServerVersion() (string, error) {
+	ctx, span := c.startSpan(c.ctx, "GetServerVersion")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathServerVersion, c.apiVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	// The server answers with a bare JSON string, an object with a version
+	// field is accepted too.
+	var version string
+	if err := json.Unmarshal(resp, &version); err == nil {
+		return version, nil
+	}
+	var result struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// DownloadFile returns the content of a file uploaded to the server, given
+// the path of the attachment.
+func (c *BlinkoClient) DownloadFile(filePath string) ([]byte, error) {
+	ctx, span := c.startSpan(c.ctx, "DownloadFile")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+strings.TrimPrefix(filePath, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.doRequest(req)
+}
+
+// GetServerStats returns the usage of the server. It requires an admin
+// account.
+func (c *BlinkoClient) GetServerStats() (ServerStats, error) {
+	ctx, span := c.startSpan(c.ctx, "GetServerStats")
+	defer span.End()
+
+	url := c.baseURL + fmt.Sprintf(apiPathServerStats, c.apiVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ServerStats{}, err
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return ServerStats{}, err
+	}
+	var stats ServerStats
+	if err := json.Unmarshal(resp, &stats); err != nil {
+		return ServerStats{}, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,110 @@
+package blinkogram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// newNoteListServer serves notesPerPage[page-1] notes for each page of the
+// note list along with total, left out when 0 like servers which do not
+// count the notes. It records the requested pages.
+func newNoteListServer(t *testing.T, notesPerPage []int, total int, pages *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf(apiPathGetNoteList, defaultAPIVersion) {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Page     int `json:"page"`
+			PageSize int `json:"pageSize"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		*pages = append(*pages, body.Page)
+
+		response := struct {
+			Items []BlinkoItem `json:"items"`
+			Total int          `json:"total,omitempty"`
+		}{Items: []BlinkoItem{}, Total: total}
+		if body.Page >= 1 && body.Page <= len(notesPerPage) {
+			for i := range notesPerPage[body.Page-1] {
+				response.Items = append(response.Items, BlinkoItem{ID: (body.Page-1)*body.PageSize + i + 1})
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestGetAllNotesPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		notesPerPage []int
+		total        int
+		wantNotes    int
+		wantPages    []int
+	}{
+		{
+			name:      "empty",
+			wantNotes: 0,
+			wantPages: []int{1},
+		},
+		{
+			name:         "single partial page",
+			notesPerPage: []int{42},
+			total:        42,
+			wantNotes:    42,
+			wantPages:    []int{1},
+		},
+		{
+			name:         "multiple pages with a partial last page",
+			notesPerPage: []int{allNotesPageSize, allNotesPageSize, 7},
+			total:        2*allNotesPageSize + 7,
+			wantNotes:    2*allNotesPageSize + 7,
+			wantPages:    []int{1, 2, 3},
+		},
+		{
+			name:         "multiple full pages and an empty last page",
+			notesPerPage: []int{allNotesPageSize, allNotesPageSize},
+			wantNotes:    2 * allNotesPageSize,
+			wantPages:    []int{1, 2, 3},
+		},
+		{
+			name:         "multiple full pages with a total",
+			notesPerPage: []int{allNotesPageSize, allNotesPageSize},
+			total:        2 * allNotesPageSize,
+			wantNotes:    2 * allNotesPageSize,
+			wantPages:    []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages []int
+			server := newNoteListServer(t, tt.notesPerPage, tt.total, &pages)
+			defer server.Close()
+
+			notes, err := NewBlinkoClient(server.URL).GetAllNotes()
+			if err != nil {
+				t.Fatalf("GetAllNotes() error = %v", err)
+			}
+			if len(notes) != tt.wantNotes {
+				t.Errorf("GetAllNotes() returned %d notes, want %d", len(notes), tt.wantNotes)
+			}
+			seen := map[int]bool{}
+			for _, note := range notes {
+				if seen[note.ID] {
+					t.Errorf("note %d returned twice", note.ID)
+				}
+				seen[note.ID] = true
+			}
+			if !slices.Equal(pages, tt.wantPages) {
+				t.Errorf("requested pages %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package blinkogram
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/http"
 	"os"
 	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/joho/godotenv"
@@ -10,10 +17,57 @@ import (
 )
 
 type Config struct {
-	ServerAddr    string `env:"SERVER_ADDR,required"`
-	BotToken      string `env:"BOT_TOKEN,required"`
-	BotProxyAddr  string `env:"BOT_PROXY_ADDR"`
-	Data          string `env:"DATA"`
+	ServerAddr              string        `env:"SERVER_ADDR,required"`
+	BotToken                string        `env:"BOT_TOKEN,required"`
+	BotToken2               string        `env:"BOT_TOKEN_2"`
+	BotToken3               string        `env:"BOT_TOKEN_3"`
+	BotProxyAddr            string        `env:"BOT_PROXY_ADDR"`
+	BotSocks5Proxy          string        `env:"BOT_SOCKS5_PROXY"`
+	Data                    string        `env:"DATA"`
+	AttachMaxSize           int64         `env:"ATTACH_MAX_SIZE" envDefault:"20971520"`
+	AdminUserID             int64         `env:"ADMIN_USER_ID"`
+	BotName                 string        `env:"BOT_NAME"`
+	BotCommandScope         string        `env:"BOT_COMMAND_SCOPE" envDefault:"private"`
+	MessageMaxLength        int           `env:"MESSAGE_MAX_LENGTH" envDefault:"4096"`
+	MediaGroupCacheTTL      time.Duration `env:"MEDIA_GROUP_CACHE_TTL" envDefault:"24h"`
+	RateLimit               int           `env:"RATE_LIMIT"`
+	RateLimitBurst          int           `env:"RATE_LIMIT_BURST" envDefault:"5"`
+	QueueSize               int           `env:"QUEUE_SIZE" envDefault:"100"`
+	UploadWorkers           int           `env:"UPLOAD_WORKERS" envDefault:"3"`
+	FuzzyFallback           bool          `env:"FUZZY_FALLBACK"`
+	MaxSearchResults        int           `env:"MAX_SEARCH_RESULTS" envDefault:"20"`
+	BlinkoTimeout           time.Duration `env:"BLINKO_TIMEOUT" envDefault:"30s"`
+	BlinkoRetries           int           `env:"BLINKO_RETRIES"`
+	DryRun                  bool          `env:"WITH_DRY_RUN"`
+	DefaultNotebookID       int           `env:"DEFAULT_NOTEBOOK_ID"`
+	OtelEndpoint            string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ChannelID               int64         `env:"CHANNEL_ID"`
+	ServiceToken            string        `env:"SERVICE_TOKEN"`
+	BlinkoHTTPUser          string        `env:"BLINKO_HTTP_USER"`
+	BlinkoHTTPPass          string        `env:"BLINKO_HTTP_PASS"`
+	MinServerVersion        string        `env:"MIN_SERVER_VERSION"`
+	APIVersion              string        `env:"API_VERSION" envDefault:"v1"`
+	LogLevel                string        `env:"LOG_LEVEL" envDefault:"info"`
+	MetricsFormat           string        `env:"METRICS_FORMAT"`
+	MetricsPort             string        `env:"METRICS_PORT"`
+	BlinkoCACert            string        `env:"BLINKO_CA_CERT"`
+	EventWebhookPath        string        `env:"BLINKO_EVENT_WEBHOOK_PATH"`
+	EventWebhookPort        string        `env:"BLINKO_EVENT_WEBHOOK_PORT" envDefault:"8080"`
+	EventWebhookHost        string        `env:"BLINKO_EVENT_WEBHOOK_HOST" envDefault:"127.0.0.1"`
+	EventWebhookSecret      string        `env:"BLINKO_EVENT_WEBHOOK_SECRET"`
+	SummarizeAPIURL         string        `env:"SUMMARIZE_API_URL"`
+	SummarizeThresholdChars int           `env:"SUMMARIZE_THRESHOLD_CHARS" envDefault:"500"`
+	AllowedTopicIDs         []int         `env:"ALLOWED_TOPIC_IDS" envSeparator:","`
+	ForwardTemplate         string        `env:"FORWARD_TEMPLATE" envDefault:"Forwarded from {{if .Username}}[{{.Name}}](https://t.me/{{.Username}}){{else}}{{.Name}}{{end}}\n{{.Content}}"`
+}
+
+var botTokenRegexp = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]{35}$`)
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
 }
 
 func getConfigFromEnv() (*Config, error) {
@@ -34,5 +88,96 @@ func getConfigFromEnv() (*Config, error) {
 		config.Data = "data.txt"
 	}
 	config.Data = path.Join(".", config.Data)
+	if !botTokenRegexp.MatchString(config.BotToken) {
+		return nil, errors.New("BOT_TOKEN appears malformed; expected format <id>:<secret>")
+	}
+	if config.BotToken2 != "" && !botTokenRegexp.MatchString(config.BotToken2) {
+		return nil, errors.New("BOT_TOKEN_2 appears malformed; expected format <id>:<secret>")
+	}
+	if config.BotToken3 != "" && !botTokenRegexp.MatchString(config.BotToken3) {
+		return nil, errors.New("BOT_TOKEN_3 appears malformed; expected format <id>:<secret>")
+	}
+	if config.BotCommandScope != "private" && config.BotCommandScope != "all" {
+		return nil, errors.Errorf("invalid BOT_COMMAND_SCOPE %q, expected private or all", config.BotCommandScope)
+	}
+	if config.ChannelID != 0 && config.ServiceToken == "" {
+		return nil, errors.New("SERVICE_TOKEN is required when CHANNEL_ID is set")
+	}
+	if config.BlinkoHTTPUser != "" || config.BlinkoHTTPPass != "" {
+		// The Authorization header carries the Blinko token, so the Basic
+		// Auth of reverse proxies cannot be passed along with it.
+		return nil, errors.New("BLINKO_HTTP_USER and BLINKO_HTTP_PASS are not supported, exempt the Blinko API from the Basic Auth of the proxy instead")
+	}
+	if config.BlinkoRetries < 0 {
+		return nil, errors.Errorf("invalid BLINKO_RETRIES %d, expected a non-negative number", config.BlinkoRetries)
+	}
+	if config.RateLimit < 0 || config.RateLimitBurst < 1 {
+		return nil, errors.Errorf("invalid RATE_LIMIT %d or RATE_LIMIT_BURST %d", config.RateLimit, config.RateLimitBurst)
+	}
+	if config.MessageMaxLength < 100 {
+		return nil, errors.Errorf("invalid MESSAGE_MAX_LENGTH %d, expected at least 100", config.MessageMaxLength)
+	}
+	if config.MediaGroupCacheTTL <= 0 {
+		return nil, errors.Errorf("invalid MEDIA_GROUP_CACHE_TTL %s, expected a positive duration", config.MediaGroupCacheTTL)
+	}
+	if config.EventWebhookPath != "" && !strings.HasPrefix(config.EventWebhookPath, "/") {
+		return nil, errors.Errorf("invalid BLINKO_EVENT_WEBHOOK_PATH %q, expected a path starting with /", config.EventWebhookPath)
+	}
+	if config.EventWebhookPath != "" && config.EventWebhookSecret == "" {
+		return nil, errors.New("BLINKO_EVENT_WEBHOOK_SECRET is required when BLINKO_EVENT_WEBHOOK_PATH is set")
+	}
+	if config.SummarizeThresholdChars < 0 {
+		return nil, errors.Errorf("invalid SUMMARIZE_THRESHOLD_CHARS %d, expected a non-negative number", config.SummarizeThresholdChars)
+	}
+	if config.MaxSearchResults < 1 {
+		return nil, errors.Errorf("invalid MAX_SEARCH_RESULTS %d, expected at least 1", config.MaxSearchResults)
+	}
+	if config.UploadWorkers < 1 {
+		return nil, errors.Errorf("invalid UPLOAD_WORKERS %d, expected at least 1", config.UploadWorkers)
+	}
+	config.LogLevel = strings.ToLower(config.LogLevel)
+	if _, ok := logLevels[config.LogLevel]; !ok {
+		return nil, errors.Errorf("invalid LOG_LEVEL %q, expected debug, info, warn or error", config.LogLevel)
+	}
+	if config.QueueSize < 0 {
+		return nil, errors.Errorf("invalid QUEUE_SIZE %d, expected a non-negative number", config.QueueSize)
+	}
 	return &config, nil
 }
+
+// blinkoClientOptions builds the BlinkoClient options from the config.
+func blinkoClientOptions(config *Config) ([]BlinkoClientOption, error) {
+	opts := []BlinkoClientOption{
+		WithTimeout(config.BlinkoTimeout),
+		WithRetries(config.BlinkoRetries),
+		WithAPIVersion(config.APIVersion),
+	}
+	if config.metricsEnabled() {
+		opts = append(opts, WithMetrics())
+	}
+	if config.DryRun {
+		opts = append(opts, WithDryRun())
+	}
+	if config.BlinkoCACert != "" {
+		pem, err := os.ReadFile(config.BlinkoCACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read BLINKO_CA_CERT")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", config.BlinkoCACert)
+		}
+		// Clients are built per update, so they share a single transport
+		// to reuse its connections.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		opts = append(opts, WithHTTPClient(&http.Client{
+			Timeout:   config.BlinkoTimeout,
+			Transport: transport,
+		}))
+	}
+	return opts, nil
+}
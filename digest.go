@@ -0,0 +1,161 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const digestSnippetLength = 100
+
+func (s *Service) dailyDigestHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	arg := strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/daily_digest"))
+
+	if arg == "off" {
+		s.store.DeleteDailyDigestTime(userID)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Daily digest cancelled",
+		})
+		return
+	}
+
+	clock, timezone, _ := strings.Cut(arg, " ")
+	digestTime, err := time.Parse("15:04", clock)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /daily_digest <HH:MM> [timezone] or /daily_digest off",
+		})
+		return
+	}
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Unknown timezone %q, expected e.g. Europe/Paris", timezone), err))
+			return
+		}
+		s.store.SetUserTimezone(userID, timezone)
+	}
+
+	s.store.SetDailyDigestTime(userID, digestTime)
+	timezone = s.store.GetUserTimezone(userID)
+	if timezone == "" {
+		timezone = "server time"
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Daily digest scheduled at %s (%s)", digestTime.Format("15:04"), timezone),
+	})
+}
+
+// startDailyDigest starts a goroutine that sends the daily digest to every
+// user whose digest time has arrived.
+func (s *Service) startDailyDigest(ctx context.Context) {
+	s.goWork(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.sendDailyDigests(ctx, now)
+			}
+		}
+	})
+}
+
+// userLocation returns the timezone of the user, the one of the server if
+// unset or unknown.
+func (s *Service) userLocation(userID int64) *time.Location {
+	timezone := s.store.GetUserTimezone(userID)
+	if timezone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		slog.Warn("unknown user timezone", slog.Int64("userID", userID), slog.String("timezone", timezone), slog.Any("err", err))
+		return time.Local
+	}
+	return location
+}
+
+// sendDailyDigests sends the digest of the users whose digest time is now
+// in their own timezone.
+func (s *Service) sendDailyDigests(ctx context.Context, now time.Time) {
+	userIDs, err := s.store.ListAllUsers()
+	if err != nil {
+		slog.Error("failed to list users", slog.Any("err", err))
+		return
+	}
+	for _, userID := range userIDs {
+		digestTime, ok := s.store.GetDailyDigestTime(userID)
+		if !ok {
+			continue
+		}
+		now := now.In(s.userLocation(userID))
+		if now.Hour() != digestTime.Hour() || now.Minute() != digestTime.Minute() {
+			continue
+		}
+
+		// Guard against sending the digest twice within the same minute
+		sentKey := fmt.Sprintf("digest:%d:%s", userID, now.Format("2006-01-02"))
+		if _, ok := s.cache.get(sentKey); ok {
+			continue
+		}
+		s.cache.set(sentKey, true, 24*time.Hour)
+
+		if err := s.sendDailyDigest(ctx, userID, now.AddDate(0, 0, -1)); err != nil {
+			slog.Error("failed to send daily digest", slog.Int64("userID", userID), slog.Any("err", err))
+		}
+	}
+}
+
+func (s *Service) sendDailyDigest(ctx context.Context, userID int64, date time.Time) error {
+	if _, ok := s.store.GetUserAccessToken(userID); !ok {
+		return nil
+	}
+	ctx = withCorrelationID(ctx, newCorrelationID())
+	client := s.newUserClient(ctx, userID)
+
+	memos, err := client.GetNoteListByDate(date)
+	if err != nil {
+		return err
+	}
+
+	// A busy day does not fit in a single message.
+	b := s.currentBot()
+	for _, part := range splitMessage(formatDailyDigest(date, memos), s.config.MessageMaxLength) {
+		if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: s.store.GetUserChatID(userID),
+			Text:   part,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatDailyDigest(date time.Time, memos []BlinkoItem) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Daily digest for %s: %d memos\n", date.Format("2006-01-02"), len(memos)))
+	for _, memo := range memos {
+		sb.WriteString(fmt.Sprintf("\n[%d] %s", memo.ID, truncateRunes(memo.Content, digestSnippetLength)))
+	}
+	return sb.String()
+}
+
+// truncateRunes shortens text to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
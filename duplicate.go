@@ -0,0 +1,145 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const duplicateSnippetLength = 50
+
+// findDuplicates groups the memos by normalized content and returns the
+// groups with more than one memo, each sorted from oldest to newest.
+func findDuplicates(memos []BlinkoItem) [][]BlinkoItem {
+	groups := map[string][]BlinkoItem{}
+	for _, memo := range memos {
+		key := strings.ToLower(strings.TrimSpace(memo.Content))
+		if key == "" {
+			continue
+		}
+		groups[key] = append(groups[key], memo)
+	}
+
+	var duplicates [][]BlinkoItem
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+		duplicates = append(duplicates, group)
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i][0].ID < duplicates[j][0].ID })
+	return duplicates
+}
+
+func (s *Service) duplicateCheckHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memos, err := client.GetAllNotes()
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to get memos"))
+		return
+	}
+
+	duplicates := findDuplicates(memos)
+	if len(duplicates) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "No duplicates found.",
+		})
+		return
+	}
+
+	for _, group := range duplicates {
+		ids := make([]string, 0, len(group))
+		for _, memo := range group {
+			ids = append(ids, strconv.Itoa(memo.ID))
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text: fmt.Sprintf("Found %d duplicates for content: '%s' — IDs: %s",
+				len(group), truncateRunes(strings.TrimSpace(group[0].Content), duplicateSnippetLength), strings.Join(ids, ", ")),
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{
+					{
+						{
+							Text:         "Delete duplicates",
+							CallbackData: fmt.Sprintf("dedup %d", group[0].ID),
+						},
+					},
+				},
+			},
+		})
+	}
+}
+
+// deleteDuplicates deletes every memo with the same content as memo, except
+// the oldest one.
+func (s *Service) deleteDuplicates(ctx context.Context, b *bot.Bot, client *BlinkoClient, update *models.Update, memo BlinkoItem) {
+	memos, err := client.GetAllNotes()
+	if err != nil {
+		slog.Error("failed to get memos", slog.Any("err", err))
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            "Failed to get memos",
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	var ids []int
+	var duplicates []string
+	for _, group := range findDuplicates(memos) {
+		if !containsMemo(group, memo.ID) {
+			continue
+		}
+		for _, duplicate := range group[1:] {
+			ids = append(ids, duplicate.ID)
+			duplicates = append(duplicates, strconv.Itoa(duplicate.ID))
+		}
+	}
+
+	var deleted, failed []string
+	if err := client.BulkDeleteNotes(ids); err != nil {
+		slog.Error("failed to delete memos", slog.Any("ids", ids), slog.Any("err", err))
+		failed = duplicates
+	} else {
+		deleted = duplicates
+	}
+
+	text := fmt.Sprintf("Deleted duplicates: %s", strings.Join(deleted, ", "))
+	if len(deleted) == 0 {
+		text = "No duplicates deleted."
+	}
+	if len(failed) > 0 {
+		text += fmt.Sprintf("\nFailed to delete: %s", strings.Join(failed, ", "))
+	}
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+		MessageID: update.CallbackQuery.Message.Message.ID,
+		Text:      text,
+	})
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		Text:            "Duplicates deleted",
+	})
+}
+
+func containsMemo(memos []BlinkoItem, id int) bool {
+	for _, memo := range memos {
+		if memo.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package blinkogram
+
+// UserError is an error whose message is safe to show to users. Any other
+// error is considered internal and is only logged.
+type UserError struct {
+	Message string
+	Err     error
+}
+
+func NewUserError(message string, err error) *UserError {
+	return &UserError{
+		Message: message,
+		Err:     err,
+	}
+}
+
+func (e *UserError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
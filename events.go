@@ -0,0 +1,109 @@
+package blinkogram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	noteViewedEvent = "note.viewed"
+
+	// eventSecretHeader carries BLINKO_EVENT_WEBHOOK_SECRET.
+	eventSecretHeader = "X-Blinko-Event-Secret"
+)
+
+// blinkoEvent is the payload posted to BLINKO_EVENT_WEBHOOK_PATH, e.g.
+// {"event": "note.viewed", "noteId": 42}.
+type blinkoEvent struct {
+	Event  string `json:"event"`
+	NoteID int    `json:"noteId"`
+}
+
+// startEventWebhook receives the Blinko events on BLINKO_EVENT_WEBHOOK_PATH
+// until ctx is cancelled. Events without BLINKO_EVENT_WEBHOOK_SECRET in the
+// X-Blinko-Event-Secret header are rejected.
+func (s *Service) startEventWebhook(ctx context.Context) {
+	if s.config.EventWebhookPath == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+s.config.EventWebhookPath, func(w http.ResponseWriter, r *http.Request) {
+		secret := []byte(r.Header.Get(eventSecretHeader))
+		if subtle.ConstantTimeCompare(secret, []byte(s.config.EventWebhookSecret)) != 1 {
+			http.Error(w, "invalid secret", http.StatusUnauthorized)
+			return
+		}
+		if !s.beginWork() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.work.Done()
+		var event blinkoEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		if event.Event == noteViewedEvent {
+			s.notifyNoteViewed(ctx, event.NoteID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := &http.Server{
+		Addr:              net.JoinHostPort(s.config.EventWebhookHost, s.config.EventWebhookPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("event webhook server stopped", slog.Any("err", err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+}
+
+// notifyNoteViewed tells the user who created the note through the bot that
+// it was viewed, if they turned on /notify_on_share.
+func (s *Service) notifyNoteViewed(ctx context.Context, noteID int) {
+	userID, ok := s.store.GetUserByNoteID(noteID)
+	if !ok || !s.store.GetUserNotifyOnShare(userID) {
+		return
+	}
+	if _, err := s.currentBot().SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: s.store.GetUserChatID(userID),
+		Text:   fmt.Sprintf("Your note #%d was viewed.", noteID),
+	}); err != nil {
+		slog.Warn("failed to send view notification", slog.Int64("user_id", userID), slog.Any("err", err))
+	}
+}
+
+func (s *Service) notifyOnShareHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	notify := !s.store.GetUserNotifyOnShare(userID)
+	s.store.SetUserNotifyOnShare(userID, notify)
+
+	text := "You will no longer be notified when your public notes are viewed"
+	if notify {
+		text = "You will be notified when your public notes are viewed"
+		if s.config.EventWebhookPath == "" {
+			text += ", once the bot operator sets up Blinko events"
+		}
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
@@ -0,0 +1,52 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// feedbackHandler relays the user's message to the bot operator.
+func (s *Service) feedbackHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	message := m.Message
+	feedback := strings.TrimSpace(strings.TrimPrefix(message.Text, "/feedback"))
+	if feedback == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "Usage: /feedback <message>",
+		})
+		return
+	}
+	if s.config.AdminUserID == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "Feedback is not enabled on this bot",
+		})
+		return
+	}
+
+	sender := message.From.FirstName
+	if message.From.Username != "" {
+		sender = "@" + message.From.Username
+	}
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: s.config.AdminUserID,
+		Text: fmt.Sprintf("Feedback from %s (%d) at %s:\n%s",
+			sender, message.From.ID, time.Unix(int64(message.Date), 0).UTC().Format(time.RFC3339), feedback),
+	})
+	if err != nil {
+		slog.Error("failed to send feedback to admin", slog.Int64("userID", message.From.ID), slog.Any("err", err))
+		s.sendError(b, message.Chat.ID, message.From.ID, err)
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   "Feedback sent, thank you!",
+	})
+}
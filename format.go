@@ -0,0 +1,153 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+var (
+	// urlRegexp matches bare URLs, i.e. not already part of a markdown link
+	// or an autolink.
+	urlRegexp        = regexp.MustCompile(`(^|[^(<\[])(https?://[^\s<>()\[\]]+)`)
+	underscoreRegexp = regexp.MustCompile(`__([^_\n]+)__`)
+	bulletRegexp     = regexp.MustCompile(`(?m)^(\s*)[•*] `)
+)
+
+// noteFormatters are applied in order by reformatContent.
+var noteFormatters = []func(string) string{
+	linkURLs,
+	func(text string) string {
+		return underscoreRegexp.ReplaceAllString(text, "**$1**")
+	},
+	func(text string) string {
+		return bulletRegexp.ReplaceAllString(text, "$1- ")
+	},
+}
+
+// linkURLs turns bare URLs into markdown links. Trailing punctuation is kept
+// out of the link.
+func linkURLs(text string) string {
+	return urlRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		groups := urlRegexp.FindStringSubmatch(match)
+		prefix, url := groups[1], groups[2]
+		trimmed := strings.TrimRight(url, ".,;:!?'\"")
+		return fmt.Sprintf("%s[%s](%s)%s", prefix, trimmed, trimmed, url[len(trimmed):])
+	})
+}
+
+// reformatContent applies noteFormatters to the content outside of code
+// blocks and inline code.
+func reformatContent(content string) string {
+	lines := strings.Split(content, "\n")
+	var result, text []string
+	flush := func() {
+		if len(text) > 0 {
+			result = append(result, formatText(strings.Join(text, "\n")))
+			text = nil
+		}
+	}
+	inCode := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			flush()
+			inCode = !inCode
+			result = append(result, line)
+			continue
+		}
+		if inCode {
+			result = append(result, line)
+		} else {
+			text = append(text, line)
+		}
+	}
+	flush()
+	return strings.Join(result, "\n")
+}
+
+func formatText(text string) string {
+	parts := strings.Split(text, "`")
+	for i := 0; i < len(parts); i += 2 {
+		for _, format := range noteFormatters {
+			parts[i] = format(parts[i])
+		}
+	}
+	return strings.Join(parts, "`")
+}
+
+// formatHandler shows the memo with reformatContent applied and asks for
+// confirmation before saving it.
+func (s *Service) formatHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "format")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /format <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+	formatted := reformatContent(memo.Content)
+	if formatted == memo.Content {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("Memo #%d is already formatted", memo.ID),
+		})
+		return
+	}
+
+	header := fmt.Sprintf("Preview of memo #%d:\n\n", memo.ID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   header + truncateRunes(formatted, s.config.MessageMaxLength-len([]rune(header))),
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text:         "Apply",
+						CallbackData: fmt.Sprintf("format %d", memo.ID),
+					},
+				},
+			},
+		},
+	})
+}
+
+// applyFormat saves the formatted memo once "Apply" was pressed. The
+// formatting is computed again in case the memo changed since the preview.
+func (s *Service) applyFormat(ctx context.Context, b *bot.Bot, client *BlinkoClient, update *models.Update, memo BlinkoItem) {
+	userID := update.CallbackQuery.From.ID
+	if _, err := client.UpdateNoteContent(memo.ID, reformatContent(memo.Content)); err != nil {
+		slog.Error("failed to format memo", slog.Int("memo_id", memo.ID), slog.Any("err", err))
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            s.t(userID, "update_failed"),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+		MessageID: update.CallbackQuery.Message.Message.ID,
+		Text:      fmt.Sprintf("Memo #%d formatted", memo.ID),
+	})
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		Text:            s.t(userID, "memo_updated"),
+	})
+}
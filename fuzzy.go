@@ -0,0 +1,82 @@
+package blinkogram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// fuzzyPrefixLength is how much of the start of each memo is matched.
+	fuzzyPrefixLength = 100
+	fuzzyNotesTTL     = 5 * time.Minute
+)
+
+func notesCacheKey(userID int64) string {
+	return fmt.Sprintf("notes:%d", userID)
+}
+
+// fuzzyDistance returns the Levenshtein distance between the query and the
+// substring of text closest to it, so that a query matches anywhere in the
+// text and only typos count.
+func fuzzyDistance(query, text string) int {
+	q, t := []rune(query), []rune(text)
+	// prev[j] is the distance between the query prefix and the best
+	// substring of text ending at j. Starting anywhere in text is free.
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for i := 1; i <= len(q); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if q[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	best := len(q)
+	for _, distance := range prev {
+		best = min(best, distance)
+	}
+	return best
+}
+
+// fuzzyMatch returns the memos whose first fuzzyPrefixLength characters
+// contain the query with at most one typo per four characters.
+func fuzzyMatch(query string, memos []BlinkoItem) []BlinkoItem {
+	query = strings.ToLower(query)
+	maxDistance := (utf8.RuneCountInString(query) + 3) / 4
+
+	var matches []BlinkoItem
+	for _, memo := range memos {
+		prefix := []rune(strings.ToLower(memo.Content))
+		if len(prefix) > fuzzyPrefixLength {
+			prefix = prefix[:fuzzyPrefixLength]
+		}
+		if fuzzyDistance(query, string(prefix)) <= maxDistance {
+			matches = append(matches, memo)
+		}
+	}
+	return matches
+}
+
+// fuzzySearch matches the query against all memos of the user, which are
+// cached for a few minutes since every fallback search needs them.
+func (s *Service) fuzzySearch(client *BlinkoClient, userID int64, query string) ([]BlinkoItem, error) {
+	var memos []BlinkoItem
+	if cached, ok := s.cache.get(notesCacheKey(userID)); ok {
+		memos = cached.([]BlinkoItem)
+	} else {
+		var err error
+		memos, err = client.GetAllNotes()
+		if err != nil {
+			return nil, err
+		}
+		s.cache.set(notesCacheKey(userID), memos, fuzzyNotesTTL)
+	}
+	return fuzzyMatch(query, memos), nil
+}
@@ -0,0 +1,130 @@
+package blinkogram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	importMaxSize          = 10 << 20
+	importInterval         = time.Second
+	importProgressInterval = 10
+)
+
+// importHandler imports a JSON array of memos from a URL or a Telegram
+// document, sent with the /import caption or replied to with /import.
+func (s *Service) importHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	message := m.Message
+	userID := message.From.ID
+	if _, ok := s.store.GetUserAccessToken(userID); !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "Please start the bot with /start <access_token>",
+		})
+		return
+	}
+
+	source := strings.TrimSpace(strings.TrimPrefix(message.Text, "/import"))
+	document := message.Document
+	if document == nil && message.ReplyToMessage != nil {
+		document = message.ReplyToMessage.Document
+	}
+	if source == "" && document == nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "Usage: /import <json_url>, or send a JSON file with the /import caption",
+		})
+		return
+	}
+
+	var data []byte
+	if source == "" {
+		if document.FileSize > importMaxSize {
+			s.sendError(b, message.Chat.ID, message.From.ID, NewUserError(fmt.Sprintf("The file exceeds the size limit of %d bytes", importMaxSize), nil))
+			return
+		}
+		file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: document.FileID})
+		if err != nil {
+			s.sendError(b, message.Chat.ID, message.From.ID, errors.Wrap(err, "failed to get file"))
+			return
+		}
+		// Telegram files are fetched like the other resources, as the Bot
+		// API server may be local or only reachable through the proxy.
+		data, err = s.downloadFile(ctx, b, file, importMaxSize)
+		if err != nil {
+			s.sendError(b, message.Chat.ID, message.From.ID, errors.Wrap(err, "failed to download file"))
+			return
+		}
+	} else {
+		var err error
+		data, _, err = downloadURL(ctx, source, importMaxSize)
+		if err != nil {
+			s.sendError(b, message.Chat.ID, message.From.ID, errors.Wrap(err, "failed to download file"))
+			return
+		}
+	}
+
+	var items []BlinkoItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		s.sendError(b, message.Chat.ID, message.From.ID, NewUserError("Invalid backup file, expected a JSON array of memos", err))
+		return
+	}
+
+	// Use a dedicated client since the import outlives this update.
+	client := s.newUserClient(ctx, userID)
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   fmt.Sprintf("Importing %d memos...", len(items)),
+	})
+	s.goWork(func() { s.importMemos(ctx, b, client, message.Chat.ID, items) })
+}
+
+func (s *Service) importMemos(ctx context.Context, b *bot.Bot, client *BlinkoClient, chatID int64, items []BlinkoItem) {
+	ticker := time.NewTicker(importInterval)
+	defer ticker.Stop()
+
+	imported, failed := 0, 0
+	for i, item := range items {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		// Always create new memos, resources of the backup belong to another server.
+		item.ID = 0
+		item.Attachments = nil
+		if item.Type == nil {
+			item.Type = typePtr(NoteTypeFlash)
+		}
+		if _, err := client.UpsertBlinko(item); err != nil {
+			slog.Error("failed to import memo", slog.Int("index", i), slog.Any("err", err))
+			failed++
+		} else {
+			imported++
+		}
+
+		if done := i + 1; done%importProgressInterval == 0 && done < len(items) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   fmt.Sprintf("Imported %d/%d memos", done, len(items)),
+			})
+		}
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("Import finished: %d imported, %d failed", imported, failed),
+	})
+}
@@ -0,0 +1,114 @@
+package blinkogram
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Locales maps a locale code to its reply message strings. Only the replies
+// to saved memos and memo buttons, and the errors, are translated, the other
+// commands reply in English.
+type Locales map[string]map[string]string
+
+func loadLocales() (Locales, error) {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	locales := Locales{}
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, errors.Wrapf(err, "invalid locale file %s", entry.Name())
+		}
+		locales[strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))] = messages
+	}
+	if _, ok := locales[defaultLocale]; !ok {
+		return nil, errors.Errorf("missing default locale %s", defaultLocale)
+	}
+	return locales, nil
+}
+
+// has reports whether the locale is available.
+func (l Locales) has(locale string) bool {
+	_, ok := l[locale]
+	return ok
+}
+
+// codes returns the available locale codes in alphabetical order.
+func (l Locales) codes() []string {
+	codes := make([]string, 0, len(l))
+	for code := range l {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// confirmationKeys are the messages confirming a memo, whose replies are
+// appended to the memo.
+var confirmationKeys = []string{"content_saved", "content_appended"}
+
+// parseMemoID extracts the memo ID from a bot confirmation such as
+// "Content saved as Private with 42", in any locale. Other messages are
+// ignored even if they contain a number.
+func (l Locales) parseMemoID(text string) (int, bool) {
+	for _, messages := range l {
+		for _, key := range confirmationKeys {
+			prefix, suffix, ok := strings.Cut(messages[key], "%d")
+			if !ok {
+				continue
+			}
+			id, ok := strings.CutPrefix(text, prefix)
+			if !ok {
+				continue
+			}
+			id, ok = strings.CutSuffix(id, suffix)
+			if !ok {
+				continue
+			}
+			if memoId, err := strconv.Atoi(id); err == nil {
+				return memoId, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// translate formats the message for key in the locale, falling back to the
+// default locale and finally to the key itself.
+func (l Locales) translate(locale, key string, args ...interface{}) string {
+	message, ok := l[locale][key]
+	if !ok {
+		message, ok = l[defaultLocale][key]
+	}
+	if !ok {
+		message = key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// t translates the message for key into the user's locale.
+func (s *Service) t(userID int64, key string, args ...interface{}) string {
+	return s.locales.translate(s.store.GetUserLocale(userID), key, args...)
+}
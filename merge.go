@@ -0,0 +1,98 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const mergeSeparator = "\n---\n"
+
+func (s *Service) mergeHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	args := strings.Fields(strings.TrimPrefix(m.Message.Text, "/merge"))
+	var ids []int
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(args) != 2 || len(ids) != 2 || ids[0] == ids[1] {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /merge <id1> <id2>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	var contents []string
+	var attachments []FileInfo
+	for _, id := range ids {
+		memo, err := client.GetNoteDetail(id)
+		if err != nil {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", id), err))
+			return
+		}
+		contents = append(contents, memo.Content)
+		attachments = append(attachments, memo.Attachments...)
+	}
+
+	// The files are uploaded again, as deleting the originals deletes their
+	// resources too.
+	attachments, err := copyAttachments(client, client, attachments, s.config.AttachMaxSize)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to copy attachments"))
+		return
+	}
+
+	userID := m.Message.From.ID
+	content := strings.Join(contents, mergeSeparator)
+	merged, err := s.createMemo(client, content, collectTags([]BlinkoItem{{Content: content}}), s.notebookID(userID))
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to create merged memo"))
+		return
+	}
+	s.rememberMemo(userID, merged)
+
+	// Only delete the originals once the merged memo holds all their
+	// attachments, they would be lost otherwise.
+	if len(attachments) > 0 {
+		if _, err := client.UpsertBlinko(BlinkoItem{
+			ID:          merged.ID,
+			Content:     merged.Content,
+			Attachments: attachments,
+		}); err != nil {
+			slog.Warn("failed to attach files to merged memo, keeping the originals", slog.Int("id", merged.ID), slog.Any("err", err))
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:      m.Message.Chat.ID,
+				Text:        fmt.Sprintf("Memos %d and %d merged into %d, the originals were kept as their attachments could not be copied", ids[0], ids[1], merged.ID),
+				ReplyMarkup: s.keyboard(userID, merged),
+			})
+			return
+		}
+	}
+
+	// The merged memo already exists, so failing to delete an original is not fatal.
+	for _, id := range ids {
+		if err := client.DeleteNote(id); err != nil {
+			slog.Warn("failed to delete merged memo", slog.Int("id", id), slog.Any("err", err))
+		}
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      m.Message.Chat.ID,
+		Text:        fmt.Sprintf("Memos %d and %d merged into %d", ids[0], ids[1], merged.ID),
+		ReplyMarkup: s.keyboard(userID, merged),
+	})
+}
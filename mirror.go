@@ -0,0 +1,123 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+// mirrorHandler copies a memo with its attachments to another Blinko
+// server: /mirror <id> <targetServerURL> <targetToken>.
+func (s *Service) mirrorHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	message := m.Message
+	args := strings.Fields(strings.TrimPrefix(message.Text, "/mirror"))
+	var memoId int
+	var err error
+	if len(args) == 3 {
+		memoId, err = strconv.Atoi(args[0])
+	}
+	if len(args) != 3 || err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "Usage: /mirror <id> <targetServerURL> <targetToken>",
+		})
+		return
+	}
+	targetURL, targetToken := strings.TrimSuffix(args[1], "/"), args[2]
+
+	// The message holds a token of the target server, don't leave it in the chat.
+	if _, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: message.Chat.ID, MessageID: message.ID}); err != nil {
+		slog.Warn("failed to delete /mirror message", slog.Any("err", err))
+	}
+
+	if u, err := url.Parse(targetURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.sendError(b, message.Chat.ID, message.From.ID, NewUserError(fmt.Sprintf("Invalid server URL %q, expected http(s)://host", targetURL), err))
+		return
+	}
+	source, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	target := NewBlinkoClient(targetURL, s.targetClientOptions(ctx)...)
+	target.UpdateToken(targetToken)
+	if _, err := target.GetUserDetail(); err != nil {
+		s.sendError(b, message.Chat.ID, message.From.ID, NewUserError(fmt.Sprintf("The token is not accepted by %s", targetURL), err))
+		return
+	}
+
+	memo, err := source.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, message.Chat.ID, message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	mirrored, err := mirrorMemo(source, target, memo, s.config.AttachMaxSize)
+	if err != nil {
+		s.sendError(b, message.Chat.ID, message.From.ID, errors.Wrapf(err, "failed to mirror memo %d", memoId))
+		return
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   fmt.Sprintf("Note #%d mirrored to %s as #%d", memo.ID, targetURL, mirrored.ID),
+	})
+}
+
+// mirrorMemo creates a copy of the memo on the target server. Attachments
+// are downloaded from the source and uploaded to the target again.
+func mirrorMemo(source, target *BlinkoClient, memo BlinkoItem, maxSize int64) (BlinkoItem, error) {
+	attachments, err := copyAttachments(source, target, memo.Attachments, maxSize)
+	if err != nil {
+		return BlinkoItem{}, err
+	}
+
+	return target.UpsertBlinko(BlinkoItem{
+		Type:        memo.Type,
+		Content:     memo.Content,
+		IsTop:       memo.IsTop,
+		Attachments: attachments,
+	})
+}
+
+// copyAttachments downloads the attachments from the source and uploads
+// them to the target as new resources, which outlive the original memo.
+func copyAttachments(source, target *BlinkoClient, attachments []FileInfo, maxSize int64) ([]FileInfo, error) {
+	var copied []FileInfo
+	for _, attachment := range attachments {
+		if maxSize > 0 && int64(attachment.Size) > maxSize {
+			return nil, NewUserError(fmt.Sprintf("Attachment %s is larger than %d bytes", attachment.FileName, maxSize), nil)
+		}
+		data, err := source.DownloadFile(attachment.FilePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to download attachment %s", attachment.FileName)
+		}
+		resource, err := target.UploadFile(data, attachment.FileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to upload attachment %s", attachment.FileName)
+		}
+		copied = append(copied, resource)
+	}
+	return copied, nil
+}
+
+// targetClientOptions returns the options of the client of a server given
+// by a user: none of the credentials, metrics or CA of SERVER_ADDR, and only
+// public addresses are reached.
+func (s *Service) targetClientOptions(ctx context.Context) []BlinkoClientOption {
+	opts := []BlinkoClientOption{
+		WithHTTPClient(newPublicHTTPClient(s.config.BlinkoTimeout)),
+		WithRetries(s.config.BlinkoRetries),
+		WithContext(ctx),
+	}
+	if s.config.DryRun {
+		opts = append(opts, WithDryRun())
+	}
+	return opts
+}
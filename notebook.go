@@ -0,0 +1,49 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// notebookID returns the notebook new memos of the user are saved to,
+// falling back to DEFAULT_NOTEBOOK_ID.
+func (s *Service) notebookID(userID int64) int {
+	if id := s.store.GetUserDefaultNotebook(userID); id != 0 {
+		return id
+	}
+	return s.config.DefaultNotebookID
+}
+
+func (s *Service) notebookHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	arg := strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/notebook"))
+
+	if arg == "off" {
+		s.store.SetUserDefaultNotebook(userID, 0)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Default notebook cleared",
+		})
+		return
+	}
+
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /notebook <id> or /notebook off",
+		})
+		return
+	}
+
+	s.store.SetUserDefaultNotebook(userID, id)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("New memos will be saved to notebook %d", id),
+	})
+}
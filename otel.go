@@ -0,0 +1,43 @@
+package blinkogram
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/wolfsilver/blinko-telegram"
+
+// tracer is resolved through the global provider so that spans are no-ops
+// until setupTracing installs an exporter.
+var tracer = otel.Tracer(tracerName)
+
+// setupTracing exports spans to the OTLP endpoint. Without an endpoint the
+// no-op tracer stays in place. The returned function flushes the pending
+// spans.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create OTLP exporter")
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// recordError marks the span as failed.
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,153 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	pinAllInterval  = 500 * time.Millisecond
+	pinAllBatchSize = 10
+)
+
+func (s *Service) pinToggleHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "pin_toggle")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /pin_toggle <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	s.pinMutex.Lock()
+	memo, err := client.TogglePin(memoId)
+	s.pinMutex.Unlock()
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to toggle pin of memo %d", memoId), err))
+		return
+	}
+
+	text := fmt.Sprintf("Memo #%d unpinned", memo.ID)
+	if memo.IsTop {
+		text = fmt.Sprintf("Memo #%d pinned 📌", memo.ID)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+// pinAllHandler asks for confirmation before pinning or unpinning every memo
+// of the user.
+func (s *Service) pinAllHandler(ctx context.Context, b *bot.Bot, m *models.Update, pin bool) {
+	if _, ok := s.authorize(ctx, b, m); !ok {
+		return
+	}
+
+	action, text := "unpin_all", "Unpin all your memos?"
+	if pin {
+		action, text = "pin_all", "Pin all your memos?"
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text:         "Confirm",
+						CallbackData: action + " confirm",
+					},
+				},
+			},
+		},
+	})
+}
+
+// pinAll pins or unpins every memo of the user once the confirmation button
+// was pressed, reporting the progress in the confirmation message.
+func (s *Service) pinAll(ctx context.Context, b *bot.Bot, update *models.Update, pin bool) {
+	userID := update.CallbackQuery.From.ID
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	messageID := update.CallbackQuery.Message.Message.ID
+
+	// Use a dedicated client since pinning outlives this update.
+	client := s.newUserClient(ctx, userID)
+	memos, err := client.GetAllNotes()
+	if err != nil {
+		slog.Error("failed to get memos", slog.Any("err", err))
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            "Failed to get memos",
+			ShowAlert:       true,
+		})
+		return
+	}
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	var pending []BlinkoItem
+	for _, memo := range memos {
+		if memo.IsTop != pin {
+			pending = append(pending, memo)
+		}
+	}
+	s.goWork(func() { s.setAllPinned(ctx, b, client, chatID, messageID, pending, pin) })
+}
+
+func (s *Service) setAllPinned(ctx context.Context, b *bot.Bot, client *BlinkoClient, chatID int64, messageID int, memos []BlinkoItem, pin bool) {
+	verb := "Unpinned"
+	if pin {
+		verb = "Pinned"
+	}
+	progress := func(text string) {
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      text,
+		})
+	}
+	progress(fmt.Sprintf("%s 0/%d memos", verb, len(memos)))
+
+	ticker := time.NewTicker(pinAllInterval)
+	defer ticker.Stop()
+
+	updated, failed := 0, 0
+	for i, memo := range memos {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		if _, err := client.UpsertBlinko(BlinkoItem{
+			ID:      memo.ID,
+			Content: memo.Content,
+			IsTop:   pin,
+		}); err != nil {
+			slog.Error("failed to update memo", slog.Int("memo_id", memo.ID), slog.Any("err", err))
+			failed++
+		} else {
+			updated++
+		}
+
+		if done := i + 1; done%pinAllBatchSize == 0 && done < len(memos) {
+			progress(fmt.Sprintf("%s %d/%d memos", verb, done, len(memos)))
+		}
+	}
+
+	progress(fmt.Sprintf("%s %d memos, %d failed", verb, updated, failed))
+}
@@ -0,0 +1,134 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/pkg/errors"
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 60 * time.Second
+
+	// Polling restarts, switching to another bot token if any, once rate
+	// limited maxRateLimitErrors times within rateLimitWindow.
+	maxRateLimitErrors = 3
+	rateLimitWindow    = 5 * time.Minute
+)
+
+// runPolling runs the bot polling loop and restarts it with exponential
+// backoff when pollingErrorHandler stops it, switching to another bot token
+// if any. It returns when ctx is cancelled or the error cannot be recovered
+// by retrying, e.g. an invalid bot token.
+func (s *Service) runPolling(ctx context.Context) {
+	backoff := minReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		runCtx, cancel := context.WithCancel(ctx)
+		s.pollingMutex.Lock()
+		s.pollingCancel = cancel
+		s.pollingErr = nil
+		s.pollingMutex.Unlock()
+
+		startedAt := time.Now()
+		s.currentBot().Start(runCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.pollingMutex.Lock()
+		err := s.pollingErr
+		s.pollingMutex.Unlock()
+		if errors.Is(err, bot.ErrorUnauthorized) {
+			// The token is revoked, continue with the next one if any. The
+			// revoked token must not be polled again.
+			revoked := s.currentBot()
+			token, ok := s.tokenRotator.drop()
+			if !ok || !s.switchBot(ctx, token) {
+				slog.Error("bot polling stopped with non-retryable error", slog.Any("err", err))
+				return
+			}
+			s.abandonPending(revoked)
+			backoff = minReconnectBackoff
+			attempt = 0
+			continue
+		}
+		var tooManyRequests *bot.TooManyRequestsError
+		if errors.As(err, &tooManyRequests) {
+			if token, ok := s.tokenRotator.next(); ok && s.switchBot(ctx, token) {
+				backoff = minReconnectBackoff
+				attempt = 0
+				continue
+			}
+		}
+
+		// Start over if the previous run was healthy for a while
+		if time.Since(startedAt) > maxReconnectBackoff {
+			backoff = minReconnectBackoff
+			attempt = 1
+		}
+		slog.Warn("bot polling failed, reconnecting", slog.Int("attempt", attempt), slog.Duration("backoff", backoff), slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// switchBot replaces the bot with one using the token. It reports whether
+// the switch succeeded.
+func (s *Service) switchBot(ctx context.Context, token string) bool {
+	b, err := s.newBot(token)
+	if err != nil {
+		slog.Error("failed to create bot for the next token", slog.Any("err", err))
+		return false
+	}
+	s.setBot(b)
+	s.setupBot(ctx)
+	slog.Warn("bot polling switched to the next token", slog.String("username", s.currentBotUsername()))
+	return true
+}
+
+// pollingErrorHandler records the polling error and stops the current
+// polling run when it cannot recover by itself, i.e. the token is revoked
+// or keeps being rate limited, so that runPolling can switch tokens. The bot
+// retries the other errors with its own backoff.
+func (s *Service) pollingErrorHandler(err error) {
+	slog.Error("bot polling error", slog.Any("err", err))
+
+	s.pollingMutex.Lock()
+	defer s.pollingMutex.Unlock()
+	if !s.unrecoverable(err) {
+		return
+	}
+	s.pollingErr = err
+	s.rateLimitErrors = nil
+	if s.pollingCancel != nil {
+		s.pollingCancel()
+	}
+}
+
+// unrecoverable reports whether polling must restart after err. It must be
+// called with pollingMutex held.
+func (s *Service) unrecoverable(err error) bool {
+	if errors.Is(err, bot.ErrorUnauthorized) {
+		return true
+	}
+	var tooManyRequests *bot.TooManyRequestsError
+	if !errors.As(err, &tooManyRequests) {
+		return false
+	}
+	now := time.Now()
+	s.rateLimitErrors = slices.DeleteFunc(append(s.rateLimitErrors, now), func(t time.Time) bool {
+		return now.Sub(t) > rateLimitWindow
+	})
+	return len(s.rateLimitErrors) >= maxRateLimitErrors
+}
@@ -0,0 +1,84 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	blinkoRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blinko_requests_total",
+		Help: "Number of requests sent to the Blinko server.",
+	}, []string{"method", "path", "status_code"})
+	blinkoRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blinko_request_duration_seconds",
+		Help:    "Duration of the requests sent to the Blinko server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	registerMetricsOnce sync.Once
+)
+
+// WithMetrics records the requests of the client in the Prometheus metrics.
+func WithMetrics() BlinkoClientOption {
+	return func(c *BlinkoClient) {
+		registerMetricsOnce.Do(func() {
+			prometheus.MustRegister(blinkoRequestsTotal, blinkoRequestDuration)
+		})
+		c.metrics = true
+	}
+}
+
+// observeBlinkoRequest records a request that started at start and ended
+// with err. Network errors are counted with the "error" status code.
+func observeBlinkoRequest(req *http.Request, start time.Time, err error) {
+	statusCode := strconv.Itoa(http.StatusOK)
+	if err != nil {
+		statusCode = "error"
+		var blinkoErr *BlinkoError
+		if errors.As(err, &blinkoErr) {
+			statusCode = strconv.Itoa(blinkoErr.StatusCode)
+		}
+	}
+	blinkoRequestsTotal.WithLabelValues(req.Method, req.URL.Path, statusCode).Inc()
+	blinkoRequestDuration.WithLabelValues(req.Method, req.URL.Path).Observe(time.Since(start).Seconds())
+}
+
+// metricsEnabled reports whether the Prometheus metrics are configured.
+func (c *Config) metricsEnabled() bool {
+	return c.MetricsFormat == "prometheus" && c.MetricsPort != ""
+}
+
+// startMetricsServer serves the Prometheus metrics on METRICS_PORT until
+// ctx is cancelled.
+func (s *Service) startMetricsServer(ctx context.Context) {
+	if !s.config.metricsEnabled() {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Addr:              net.JoinHostPort("", s.config.MetricsPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", slog.Any("err", err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+}
@@ -0,0 +1,74 @@
+package blinkogram
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nonPublicPrefixes are the ranges not covered by the netip.Addr methods
+// which must not be reached with URLs given by users.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddr reports whether addr is a public unicast address, i.e. not
+// loopback, private (RFC 1918), link-local such as 169.254.169.254, etc.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly is a net.Dialer.Control rejecting connections to
+// non-public addresses. It runs after the name is resolved and for every
+// redirect, so neither redirects nor DNS rebinding can get around it.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addr) {
+		return errors.Errorf("connection to non-public address %s refused", addr)
+	}
+	return nil
+}
+
+// newPublicHTTPClient returns an HTTP client which only connects to public
+// addresses, for the URLs given by users. No proxy is used, as the check
+// would apply to the proxy instead of the server.
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: dialPublicOnly,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   10 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	minQueueRetryBackoff = 5 * time.Second
+	maxQueueRetryBackoff = 5 * time.Minute
+)
+
+// pendingUpdate is a message that could not be saved because Blinko was
+// unavailable, along with the memo content and tags already built from it
+// and the bot which received it, as its file IDs are only valid for that bot.
+type pendingUpdate struct {
+	bot     *bot.Bot
+	update  *models.Update
+	content string
+	tags    []string
+}
+
+// isUnavailable reports whether err means Blinko could not be reached or
+// failed on its side, so that retrying later may succeed.
+func isUnavailable(err error) bool {
+	var blinkoErr *BlinkoError
+	if errors.As(err, &blinkoErr) {
+		return blinkoErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// enqueue buffers the message until Blinko is back. It returns false when
+// the queue is full.
+func (s *Service) enqueue(b *bot.Bot, m *models.Update, content string, tags []string) bool {
+	select {
+	case s.queue <- pendingUpdate{bot: b, update: m, content: content, tags: tags}:
+		slog.Info("queued message while blinko is unavailable", slog.Int64("user_id", m.Message.From.ID), slog.Int("queued", len(s.queue)))
+		return true
+	default:
+		slog.Warn("message queue is full, dropping message", slog.Int64("user_id", m.Message.From.ID))
+		return false
+	}
+}
+
+// startQueueDrain saves the queued messages in the background, one at a
+// time and in order, waiting for Blinko to recover between attempts.
+func (s *Service) startQueueDrain(ctx context.Context) {
+	s.goWork(func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pending := <-s.queue:
+				s.drainPending(ctx, pending)
+			}
+		}
+	})
+}
+
+func (s *Service) drainPending(ctx context.Context, pending pendingUpdate) {
+	message := pending.update.Message
+	userID := message.From.ID
+	backoff := minQueueRetryBackoff
+	for {
+		ctx := withCorrelationID(ctx, newCorrelationID())
+		if _, ok := s.store.GetUserAccessToken(userID); !ok {
+			slog.Warn("dropping queued message of logged out user", slog.Int64("user_id", userID))
+			return
+		}
+		client := s.newUserClient(ctx, userID)
+
+		memo, err := s.handleMemoCreation(ctx, client, pending.update, pending.content, pending.tags)
+		if err == nil {
+			s.confirmMemo(ctx, pending.bot, client, pending.update, memo)
+			return
+		}
+		if !isUnavailable(err) {
+			pending.bot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: message.Chat.ID,
+				Text:   s.t(userID, "create_failed"),
+			})
+			return
+		}
+
+		slog.Warn("blinko still unavailable, retrying queued message", slog.Duration("backoff", backoff), slog.Any("err", err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxQueueRetryBackoff)
+	}
+}
+
+// abandonPending drops the queued messages received by the bot, once its
+// token is revoked: their files can no longer be downloaded and the users
+// can no longer be answered. The other messages are queued again.
+func (s *Service) abandonPending(b *bot.Bot) {
+	var kept []pendingUpdate
+	for len(s.queue) > 0 {
+		select {
+		case pending := <-s.queue:
+			if pending.bot != b {
+				kept = append(kept, pending)
+				continue
+			}
+			slog.Warn("dropping queued message of revoked bot", slog.Int64("user_id", pending.update.Message.From.ID))
+		default:
+		}
+	}
+	for _, pending := range kept {
+		if !s.enqueue(pending.bot, pending.update, pending.content, pending.tags) {
+			return
+		}
+	}
+}
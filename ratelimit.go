@@ -0,0 +1,42 @@
+package blinkogram
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// userLimiter returns the token bucket of the user, creating it on first use.
+func (s *Service) userLimiter(userID int64) *rate.Limiter {
+	s.limitersMutex.Lock()
+	defer s.limitersMutex.Unlock()
+
+	limiter, ok := s.limiters[userID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(s.config.RateLimit)), s.config.RateLimitBurst)
+		s.limiters[userID] = limiter
+	}
+	return limiter
+}
+
+// throttle takes a token from the bucket of the user. When the bucket is
+// empty, the token is left in place and the time to wait is returned.
+func (s *Service) throttle(userID int64) (time.Duration, bool) {
+	if s.config.RateLimit <= 0 {
+		return 0, true
+	}
+
+	now := time.Now()
+	reservation := s.userLimiter(userID).ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// waitSeconds rounds the delay up to whole seconds for display.
+func waitSeconds(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
@@ -0,0 +1,114 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// reactionActions maps reaction emoji, without variation selectors, to memo
+// actions. Only emoji enabled for reactions in the chat can be used.
+var reactionActions = map[string]string{
+	"👍": "pin",
+	"🗑": "delete",
+	"⭐": "public",
+}
+
+const confirmationCacheTTL = 24 * time.Hour
+
+func confirmationCacheKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("confirmation:%d:%d", chatID, messageID)
+}
+
+// confirmedMemo is the memo a confirmation message refers to, and the user
+// it was created for.
+type confirmedMemo struct {
+	userID int64
+	memoID int
+}
+
+// rememberConfirmation records which memo a confirmation message refers to,
+// so that reactions of the user to the message can be applied to the memo.
+func (s *Service) rememberConfirmation(message *models.Message, userID int64, memoId int) {
+	if message == nil {
+		return
+	}
+	s.cache.set(confirmationCacheKey(message.Chat.ID, message.ID), confirmedMemo{userID: userID, memoID: memoId}, confirmationCacheTTL)
+}
+
+func isMessageReaction(update *models.Update) bool {
+	return update.MessageReaction != nil
+}
+
+func (s *Service) reactionHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	ctx = withCorrelationID(ctx, newCorrelationID())
+	reaction := update.MessageReaction
+	if reaction.User == nil {
+		return
+	}
+
+	cached, ok := s.cache.get(confirmationCacheKey(reaction.Chat.ID, reaction.MessageID))
+	if !ok {
+		return
+	}
+	// In groups, only the user the memo was created for may act on it.
+	confirmed := cached.(confirmedMemo)
+	if confirmed.userID != reaction.User.ID {
+		return
+	}
+	memoId := confirmed.memoID
+
+	if _, ok := s.store.GetUserAccessToken(reaction.User.ID); !ok {
+		return
+	}
+	client := s.newUserClient(ctx, reaction.User.ID)
+
+	for _, reactionType := range reaction.NewReaction {
+		if reactionType.ReactionTypeEmoji == nil {
+			continue
+		}
+		action, ok := reactionActions[strings.ReplaceAll(reactionType.ReactionTypeEmoji.Emoji, "️", "")]
+		if !ok {
+			continue
+		}
+		if err := s.applyReaction(client, memoId, action); err != nil {
+			slog.Error("failed to apply reaction", slog.Int("id", memoId), slog.String("action", action), slog.Any("err", err))
+			s.sendError(b, reaction.Chat.ID, reaction.User.ID, err)
+			continue
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:              reaction.Chat.ID,
+			Text:                fmt.Sprintf("Memo %d: %s applied", memoId, action),
+			DisableNotification: true,
+			ReplyParameters: &models.ReplyParameters{
+				MessageID: reaction.MessageID,
+			},
+		})
+	}
+}
+
+func (s *Service) applyReaction(client *BlinkoClient, memoId int, action string) error {
+	switch action {
+	case "pin":
+		memo, err := client.GetNoteDetail(memoId)
+		if err != nil {
+			return err
+		}
+		_, err = client.UpsertBlinko(BlinkoItem{
+			ID:      memo.ID,
+			Content: memo.Content,
+			IsTop:   true,
+		})
+		return err
+	case "delete":
+		return client.DeleteNote(memoId)
+	case "public":
+		return client.ShareNote(memoId, true)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func (s *Service) markReadHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "mark_read")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /mark_read <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	// Make sure the memo exists before recording it.
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	s.store.MarkRead(m.Message.From.ID, memo.ID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Memo #%d marked as read", memo.ID),
+	})
+}
+
+func (s *Service) markUnreadHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "mark_unread")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /mark_unread <id>",
+		})
+		return
+	}
+	if _, ok := s.authorize(ctx, b, m); !ok {
+		return
+	}
+
+	text := fmt.Sprintf("Memo #%d marked as unread", memoId)
+	if !s.store.MarkUnread(m.Message.From.ID, memoId) {
+		text = fmt.Sprintf("Memo #%d is not marked as read", memoId)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+// unreadHandler lists the memos the user did not mark as read yet.
+func (s *Service) unreadHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	notes, err := client.GetAllNotes()
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Failed to get memos", err))
+		return
+	}
+	var memos []BlinkoItem
+	for _, memo := range notes {
+		if !s.store.IsRead(m.Message.From.ID, memo.ID) {
+			memos = append(memos, memo)
+		}
+	}
+	if len(memos) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "All memos are read.",
+		})
+		return
+	}
+
+	total, limit := len(memos), s.config.MaxSearchResults
+	if total > limit {
+		memos = memos[:limit]
+	}
+	s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, memos)
+	if total > limit {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("(%d unread memos, showing %d)", total, limit),
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+const regexFlag = "--regex"
+
+// replaceContent replaces every occurrence of old in content, as a regular
+// expression with useRegex, and returns the number of replacements.
+func replaceContent(content, old, replacement string, useRegex bool) (string, int, error) {
+	if !useRegex {
+		return strings.ReplaceAll(content, old, replacement), strings.Count(content, old), nil
+	}
+
+	re, err := regexp.Compile(old)
+	if err != nil {
+		return "", 0, NewUserError(fmt.Sprintf("Invalid regular expression %q", old), err)
+	}
+	count := len(re.FindAllStringIndex(content, -1))
+	return re.ReplaceAllString(content, replacement), count, nil
+}
+
+func (s *Service) searchReplaceHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	args := strings.Fields(strings.TrimPrefix(m.Message.Text, "/search_replace"))
+	useRegex := slices.Contains(args, regexFlag)
+	args = slices.DeleteFunc(args, func(arg string) bool {
+		return arg == regexFlag
+	})
+	var memoId int
+	var err error
+	if len(args) == 3 {
+		memoId, err = strconv.Atoi(args[0])
+	}
+	if len(args) != 3 || err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /search_replace <id> <old> <new> [--regex]",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	content, count, err := replaceContent(memo.Content, args[1], args[2], useRegex)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+		return
+	}
+	if count == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "No occurrences found.",
+		})
+		return
+	}
+
+	if _, err := client.UpdateNoteContent(memo.ID, content); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrapf(err, "failed to update memo %d", memo.ID))
+		return
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Replaced %d occurrences in memo #%d", count, memo.ID),
+	})
+}
@@ -0,0 +1,93 @@
+package blinkogram
+
+import (
+	"sync"
+
+	"github.com/go-telegram/bot"
+)
+
+// tokenRotator cycles through BOT_TOKEN, BOT_TOKEN_2 and BOT_TOKEN_3 so that
+// polling can continue with another bot when the current one is rate limited
+// or revoked.
+type tokenRotator struct {
+	mutex  sync.Mutex
+	tokens []string
+	index  int
+}
+
+func newTokenRotator(tokens ...string) *tokenRotator {
+	r := &tokenRotator{}
+	for _, token := range tokens {
+		if token != "" {
+			r.tokens = append(r.tokens, token)
+		}
+	}
+	return r
+}
+
+func (r *tokenRotator) current() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.tokens[r.index]
+}
+
+// next switches to the next token. It returns false if there is no other
+// token to switch to.
+func (r *tokenRotator) next() (string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.tokens) < 2 {
+		return "", false
+	}
+	r.index = (r.index + 1) % len(r.tokens)
+	return r.tokens[r.index], true
+}
+
+// drop removes the current token from the rotation, e.g. once it is revoked,
+// and switches to the next one. It returns false if no token is left.
+func (r *tokenRotator) drop() (string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tokens = append(r.tokens[:r.index], r.tokens[r.index+1:]...)
+	if len(r.tokens) == 0 {
+		return "", false
+	}
+	r.index %= len(r.tokens)
+	return r.tokens[r.index], true
+}
+
+// currentBot returns the bot polling for updates.
+func (s *Service) currentBot() *bot.Bot {
+	s.botMutex.RLock()
+	defer s.botMutex.RUnlock()
+	return s.bot
+}
+
+// currentBotUsername returns the username of the current bot, empty until
+// fetched by setupBot.
+func (s *Service) currentBotUsername() string {
+	s.botMutex.RLock()
+	defer s.botMutex.RUnlock()
+	return s.botUsername
+}
+
+// setBot replaces the bot polling for updates.
+func (s *Service) setBot(b *bot.Bot) {
+	s.botMutex.Lock()
+	defer s.botMutex.Unlock()
+	s.bot = b
+	s.botUsername = ""
+}
+
+// newBot creates a bot for the token with the handlers of the service.
+func (s *Service) newBot(token string) (*bot.Bot, error) {
+	b, err := bot.New(token, s.botOptions...)
+	if err != nil {
+		return nil, err
+	}
+	b.RegisterHandlerMatchFunc(isMessageReaction, s.reactionHandler)
+	if s.config.ChannelID != 0 {
+		b.RegisterHandlerMatchFunc(isChannelPost, s.channelPostHandler)
+	}
+	return b, nil
+}
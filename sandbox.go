@@ -0,0 +1,48 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const sandboxPrefix = "[SANDBOX] Would create: "
+
+func (s *Service) sandboxHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	text := "Sandbox mode on, messages will be previewed but not saved"
+	switch strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/sandbox")) {
+	case "on":
+		s.store.SetUserSandbox(userID, true)
+	case "off":
+		s.store.SetUserSandbox(userID, false)
+		text = "Sandbox mode off, messages will be saved again"
+	default:
+		text = "Usage: /sandbox on or /sandbox off"
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+// sendSandboxPreview replies with the memo content instead of saving it.
+// The content is sent as plain text when Telegram rejects its Markdown.
+func (s *Service) sendSandboxPreview(ctx context.Context, b *bot.Bot, message *models.Message, content string) {
+	params := &bot.SendMessageParams{
+		ChatID:    message.Chat.ID,
+		Text:      sandboxPrefix + wrapCodeBlock(content, codeBlockWidth),
+		ParseMode: models.ParseModeMarkdown,
+		ReplyParameters: &models.ReplyParameters{
+			MessageID: message.ID,
+		},
+	}
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		slog.Warn("failed to send sandbox preview as markdown", slog.Any("err", err))
+		params.ParseMode = ""
+		b.SendMessage(ctx, params)
+	}
+}
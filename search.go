@@ -0,0 +1,320 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+func (s *Service) searchHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	searchString := sanitizeQuery(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/search")))
+	searchString, asFile := parseFileFlag(searchString)
+	searchString, sortBy, err := parseSortOperator(searchString)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+		return
+	}
+	query, exclusions := parseExclusions(searchString)
+	// The flags and operators do not count towards the query length.
+	if length := utf8.RuneCountInString(query); length < minSearchQueryLength || length > maxSearchQueryLength {
+		text := "Search query too short"
+		if length > maxSearchQueryLength {
+			text = "Query too long"
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   text,
+		})
+		return
+	}
+	slog.Debug("searching memos", slog.String("query", query))
+
+	client := s.newUserClient(ctx, userID)
+
+	var items []BlinkoItem
+	if asFile {
+		items, err = client.SearchAllNotes(query)
+	} else {
+		var results SearchResult
+		results, err = client.SearchNotes(SearchParams{Query: query})
+		items = results.Items
+		if err == nil && len(items) == 0 && s.config.FuzzyFallback {
+			items, err = s.fuzzySearch(client, userID, query)
+		}
+	}
+
+	if err != nil {
+		slog.Error("failed to search memos", slog.Any("err", err))
+		return
+	}
+
+	memos, excluded := excludeMemos(items, exclusions)
+	sortMemos(memos, sortBy)
+
+	var notices []string
+	if excluded > 0 {
+		notices = append(notices, fmt.Sprintf("%d results excluded", excluded))
+	}
+	if asFile {
+		s.sendMemoFile(ctx, b, m.Message.From.ID, m.Message.Chat.ID, query, memos)
+	} else {
+		// The document holds every result, only the list is limited.
+		if limit := s.config.MaxSearchResults; len(memos) > limit {
+			memos = memos[:limit]
+			notices = append(notices, fmt.Sprintf("(results truncated to %d)", limit))
+		}
+		s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, memos)
+	}
+	if len(notices) > 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   strings.Join(notices, "\n"),
+		})
+	}
+}
+
+const searchInUsage = "Usage: /search_in content <query>, /search_in tag <tag> or /search_in id <id>"
+
+// searchInHandler searches a single field of the memos: the content, a tag
+// or the ID.
+func (s *Service) searchInHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	field, query, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/search_in")), " ")
+	query = sanitizeQuery(query)
+	var params SearchParams
+	var memoId int
+	ok := query != ""
+	switch field {
+	case "content":
+		params.Query = query
+	case "tag":
+		params.Tag = strings.TrimPrefix(query, "#")
+	case "id":
+		var err error
+		memoId, err = strconv.Atoi(query)
+		ok = ok && err == nil
+	default:
+		ok = false
+	}
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   searchInUsage,
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	if field == "id" {
+		memo, err := client.GetNoteDetail(memoId)
+		var blinkoErr *BlinkoError
+		if errors.As(err, &blinkoErr) && blinkoErr.IsNotFound() {
+			s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, nil)
+			return
+		}
+		if err != nil {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+			return
+		}
+		s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, []BlinkoItem{memo})
+		return
+	}
+
+	results, err := client.SearchNotes(params)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Failed to search memos", err))
+		return
+	}
+	memos := results.Items
+	limit := s.config.MaxSearchResults
+	truncated := len(memos) > limit
+	if truncated {
+		memos = memos[:limit]
+	}
+	s.sendMemoList(ctx, b, m.Message.From.ID, m.Message.Chat.ID, memos)
+	if truncated {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("(results truncated to %d)", limit),
+		})
+	}
+}
+
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 200
+)
+
+// sanitizeQuery strips null bytes and control characters from the search
+// query. Line breaks and tabs are turned into spaces.
+func sanitizeQuery(query string) string {
+	query = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, query)
+	return strings.TrimSpace(query)
+}
+
+const fileFlag = "--file"
+
+// parseFileFlag removes the --file flag from the search query and reports
+// whether it was given.
+func parseFileFlag(searchString string) (string, bool) {
+	fields := strings.Fields(searchString)
+	terms := slices.DeleteFunc(slices.Clone(fields), func(field string) bool {
+		return field == fileFlag
+	})
+	return strings.Join(terms, " "), len(terms) != len(fields)
+}
+
+const maxFilenameKeywordLength = 32
+
+var unsafeFilenameRegexp = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+
+// sendMemoFile sends the memos as a single Markdown document.
+func (s *Service) sendMemoFile(ctx context.Context, b *bot.Bot, userID, chatID int64, query string, memos []BlinkoItem) {
+	if len(memos) == 0 {
+		s.sendMemoList(ctx, b, userID, chatID, memos)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Search results for %q\n", query))
+	for _, memo := range memos {
+		sb.WriteString(fmt.Sprintf("\n## #%d", memo.ID))
+		if memo.CreatedAt != nil {
+			sb.WriteString(" - " + memo.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		sb.WriteString("\n\n" + memo.Content + "\n")
+	}
+
+	keyword := []rune(strings.Trim(unsafeFilenameRegexp.ReplaceAllString(query, "_"), "_"))
+	if len(keyword) > maxFilenameKeywordLength {
+		keyword = keyword[:maxFilenameKeywordLength]
+	}
+	filename := fmt.Sprintf("search-%s-%s.md", string(keyword), time.Now().Format("2006-01-02"))
+	_, err := b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:   chatID,
+		Document: &models.InputFileUpload{Filename: filename, Data: strings.NewReader(sb.String())},
+		Caption:  fmt.Sprintf("%d memos found", len(memos)),
+	})
+	if err != nil {
+		s.sendError(b, chatID, userID, errors.Wrap(err, "failed to send search results"))
+	}
+}
+
+// memoSort is the order requested with the sort: operator. An empty field
+// keeps the order of the server.
+type memoSort struct {
+	field string
+	desc  bool
+}
+
+// parseSortOperator removes the `sort:<field>[:desc]` token from the search
+// query. The field is one of created, updated or id.
+func parseSortOperator(searchString string) (string, memoSort, error) {
+	var terms []string
+	var sortBy memoSort
+	for _, token := range strings.Fields(searchString) {
+		spec, ok := strings.CutPrefix(token, "sort:")
+		if !ok {
+			terms = append(terms, token)
+			continue
+		}
+		field, order, _ := strings.Cut(spec, ":")
+		if !slices.Contains([]string{"created", "updated", "id"}, field) || (order != "" && order != "desc") {
+			return "", memoSort{}, NewUserError(fmt.Sprintf("Invalid sort %q, expected sort:created, sort:updated or sort:id, optionally followed by :desc", token), nil)
+		}
+		sortBy = memoSort{field: field, desc: order == "desc"}
+	}
+	return strings.Join(terms, " "), sortBy, nil
+}
+
+// sortMemos orders the memos in place. Memos without a date count as the
+// oldest.
+func sortMemos(memos []BlinkoItem, sortBy memoSort) {
+	if sortBy.field == "" {
+		return
+	}
+	less := func(a, b BlinkoItem) bool {
+		switch sortBy.field {
+		case "created":
+			return timeOrZero(a.CreatedAt).Before(timeOrZero(b.CreatedAt))
+		case "updated":
+			return timeOrZero(a.UpdatedAt).Before(timeOrZero(b.UpdatedAt))
+		default:
+			return a.ID < b.ID
+		}
+	}
+	sort.SliceStable(memos, func(i, j int) bool {
+		if sortBy.desc {
+			return less(memos[j], memos[i])
+		}
+		return less(memos[i], memos[j])
+	})
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// parseExclusions splits the `-word` tokens off the search query and
+// returns the remaining query and the lowercased excluded words.
+func parseExclusions(searchString string) (string, []string) {
+	var terms, exclusions []string
+	for _, token := range strings.Fields(searchString) {
+		if len(token) > 1 && strings.HasPrefix(token, "-") {
+			exclusions = append(exclusions, strings.ToLower(token[1:]))
+			continue
+		}
+		terms = append(terms, token)
+	}
+	return strings.Join(terms, " "), exclusions
+}
+
+// excludeMemos drops the memos containing any of the excluded words,
+// case-insensitively, and returns how many were dropped.
+func excludeMemos(memos []BlinkoItem, exclusions []string) ([]BlinkoItem, int) {
+	if len(exclusions) == 0 {
+		return memos, 0
+	}
+
+	var kept []BlinkoItem
+	for _, memo := range memos {
+		content := strings.ToLower(memo.Content)
+		excluded := false
+		for _, exclusion := range exclusions {
+			if strings.Contains(content, exclusion) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, memo)
+		}
+	}
+	return kept, len(memos) - len(kept)
+}
@@ -0,0 +1,80 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// shutdownTimeout is how long Start waits for the handlers and workers to
+// finish once polling stopped, before cancelling them and closing the store.
+const shutdownTimeout = 30 * time.Second
+
+// Stop stops polling like cancelling the context of Start. Start returns
+// once the running handlers and workers are done and the store is closed.
+func (s *Service) Stop() {
+	s.stop()
+}
+
+// beginWork registers work that Start waits for before closing the store,
+// e.g. a handler. It returns false once the service is shutting down;
+// otherwise s.work.Done must be called when the work is done.
+func (s *Service) beginWork() bool {
+	s.workMutex.Lock()
+	defer s.workMutex.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.work.Add(1)
+	return true
+}
+
+// goWork runs f in a goroutine registered with beginWork.
+func (s *Service) goWork(f func()) {
+	if !s.beginWork() {
+		return
+	}
+	go func() {
+		defer s.work.Done()
+		f()
+	}()
+}
+
+// trackHandler registers the handlers with beginWork and runs them with the
+// context of the service instead of the one of the polling run, so that
+// restarting polling does not cancel the updates being handled.
+func (s *Service) trackHandler(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(_ context.Context, b *bot.Bot, update *models.Update) {
+		if !s.beginWork() {
+			slog.Warn("dropping update received while shutting down", slog.Int64("update_id", update.ID))
+			return
+		}
+		defer s.work.Done()
+		next(s.handlerCtx, b, update)
+	}
+}
+
+// waitForWork waits up to shutdownTimeout for the registered work, then
+// cancels the handlers through cancelHandlers.
+func (s *Service) waitForWork(cancelHandlers context.CancelFunc) {
+	s.workMutex.Lock()
+	s.shuttingDown = true
+	s.workMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.work.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		slog.Warn("handlers still running after shutdown timeout, cancelling them", slog.Duration("timeout", shutdownTimeout))
+		cancelHandlers()
+		<-done
+	}
+	cancelHandlers()
+}
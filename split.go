@@ -0,0 +1,132 @@
+package blinkogram
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitMessage splits text into parts of at most limit characters, so that
+// long memos fit in Telegram messages. It splits on paragraph boundaries,
+// falling back to word boundaries, then to character boundaries.
+func splitMessage(text string, limit int) []string {
+	if limit <= 0 {
+		return []string{text}
+	}
+
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		chunk := string(runes[:limit])
+		end := strings.LastIndex(chunk, "\n\n")
+		if end <= 0 {
+			end = strings.LastIndexFunc(chunk, unicode.IsSpace)
+		}
+		if end <= 0 {
+			end = len(chunk)
+		}
+
+		parts = append(parts, strings.TrimRightFunc(chunk[:end], unicode.IsSpace))
+		runes = []rune(strings.TrimLeftFunc(string(runes[len([]rune(chunk[:end])):]), unicode.IsSpace))
+	}
+	if len(runes) > 0 || len(parts) == 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+const memoListSeparator = "\n\n"
+
+// memoBatch is the text of one message of a memo list and the memos ending
+// in it.
+type memoBatch struct {
+	text  string
+	memos []BlinkoItem
+}
+
+// batchMemos packs the memos into as few messages of at most limit
+// characters as possible. Memos longer than the limit are split over several
+// messages.
+func batchMemos(memos []BlinkoItem, limit int) []memoBatch {
+	var batches []memoBatch
+	var current memoBatch
+	flush := func() {
+		if current.text != "" {
+			batches = append(batches, current)
+		}
+		current = memoBatch{}
+	}
+
+	for _, memo := range memos {
+		entry := fmt.Sprintf("[%d] %s", memo.ID, memo.Content)
+		length := utf8.RuneCountInString(entry)
+		if current.text != "" && utf8.RuneCountInString(current.text)+len(memoListSeparator)+length > limit {
+			flush()
+		}
+		if current.text == "" && length > limit {
+			parts := splitMessage(entry, limit)
+			for _, part := range parts[:len(parts)-1] {
+				batches = append(batches, memoBatch{text: part})
+			}
+			entry = parts[len(parts)-1]
+		}
+
+		if current.text != "" {
+			current.text += memoListSeparator
+		}
+		current.text += entry
+		current.memos = append(current.memos, memo)
+	}
+	flush()
+	return batches
+}
+
+// codeBlockWidth is about how many fixed-width characters fit on a phone
+// screen.
+const codeBlockWidth = 40
+
+// wrapCodeBlock wraps the lines of the ``` code blocks of content that are
+// longer than maxWidth at word boundaries, since Telegram scrolls code
+// blocks horizontally instead. Words longer than maxWidth are kept whole.
+func wrapCodeBlock(content string, maxWidth int) string {
+	lines := strings.Split(content, "\n")
+	wrapped := make([]string, 0, len(lines))
+	inBlock := false
+	for _, line := range lines {
+		if strings.Contains(line, "```") {
+			// A line with an even number of fences opens and closes a block.
+			if strings.Count(line, "```")%2 == 1 {
+				inBlock = !inBlock
+			}
+			wrapped = append(wrapped, line)
+			continue
+		}
+		if !inBlock || utf8.RuneCountInString(line) <= maxWidth {
+			wrapped = append(wrapped, line)
+			continue
+		}
+		wrapped = append(wrapped, wrapLine(line, maxWidth)...)
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// wrapLine breaks the line into lines of at most maxWidth characters at
+// spaces, keeping its indentation on the first line.
+func wrapLine(line string, maxWidth int) []string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	current := indent
+	var lines []string
+	for _, word := range strings.Fields(line) {
+		switch {
+		case current == indent:
+			current += word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > maxWidth:
+			lines = append(lines, current)
+			current = word
+		default:
+			current += " " + word
+		}
+	}
+	return append(lines, current)
+}
@@ -0,0 +1,236 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type memoStats struct {
+	Characters  int
+	Words       int
+	Sentences   int
+	Lines       int
+	Attachments int
+}
+
+func computeMemoStats(memo BlinkoItem) memoStats {
+	stats := memoStats{
+		Characters:  len([]rune(memo.Content)),
+		Words:       len(strings.Fields(memo.Content)),
+		Sentences:   countSentences(memo.Content),
+		Attachments: len(memo.Attachments),
+	}
+	if memo.Content != "" {
+		stats.Lines = strings.Count(strings.TrimRight(memo.Content, "\n"), "\n") + 1
+	}
+	return stats
+}
+
+// countSentences counts the runs of sentence-ending punctuation that
+// follow some text, so "Wait... what?!" counts as two sentences.
+func countSentences(text string) int {
+	count := 0
+	inSentence := false
+	for _, r := range text {
+		switch r {
+		case '.', '!', '?', '。', '！', '？':
+			if inSentence {
+				count++
+				inSentence = false
+			}
+		case ' ', '\t', '\n', '\r':
+		default:
+			inSentence = true
+		}
+	}
+	if inSentence {
+		count++
+	}
+	return count
+}
+
+func (s *Service) wordCountHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "word_count")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /word_count <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	stats := computeMemoStats(memo)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text: fmt.Sprintf("Memo #%d\nCharacters: %d\nWords: %d\nSentences: %d\nLines: %d\nAttachments: %d",
+			memo.ID, stats.Characters, stats.Words, stats.Sentences, stats.Lines, stats.Attachments),
+	})
+}
+
+// countMessage records a message of the user saved to Blinko.
+func (s *Service) countMessage(userID int64) {
+	if err := s.store.IncrementUserMessageCount(userID); err != nil {
+		slog.Error("failed to count message", slog.Int64("userID", userID), slog.Any("err", err))
+	}
+}
+
+func (s *Service) meHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	messageCount, err := s.store.GetUserMessageCount(userID)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+		return
+	}
+
+	rows := [][2]string{
+		{"User ID", strconv.FormatInt(userID, 10)},
+		{"Messages saved", strconv.Itoa(messageCount)},
+		{"Notes created", strconv.Itoa(s.store.GetNotesCreated(userID))},
+		{"Member since", s.creationTime(userID)},
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    m.Message.Chat.ID,
+		Text:      formatTable([2]string{"Field", "Value"}, rows),
+		ParseMode: models.ParseModeMarkdown,
+	})
+}
+
+// whoamiHandler shows what the bot knows about the user, without requiring
+// a stored access token, to troubleshoot /start.
+func (s *Service) whoamiHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	user := m.Message.From
+	token := "not set"
+	if _, ok := s.store.GetUserAccessToken(user.ID); ok {
+		if _, err := s.newUserClient(ctx, user.ID).GetUserDetail(); err != nil {
+			token = "rejected by Blinko"
+		} else {
+			token = "valid"
+		}
+	}
+
+	rows := [][2]string{
+		{"User ID", strconv.FormatInt(user.ID, 10)},
+		{"First name", user.FirstName},
+		{"Username", valueOrUnset(user.Username)},
+		{"Server URL", s.serverAddr(user.ID)},
+		{"Access token", token},
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    m.Message.Chat.ID,
+		Text:      formatTable([2]string{"Field", "Value"}, rows),
+		ParseMode: models.ParseModeMarkdown,
+	})
+}
+
+// streakDays is how far back /streak looks for the longest streak.
+const streakDays = 365
+
+// computeStreaks returns the number of consecutive days with notes ending
+// today, or yesterday when nothing was created today yet, and the longest
+// run of such days within the counted period.
+func computeStreaks(counts map[string]int, today time.Time, days int) (current, longest int) {
+	run := 0
+	for i := days - 1; i >= 0; i-- {
+		if counts[today.AddDate(0, 0, -i).Format("2006-01-02")] > 0 {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	start := today
+	if counts[today.Format("2006-01-02")] == 0 {
+		start = today.AddDate(0, 0, -1)
+	}
+	for day := start; counts[day.Format("2006-01-02")] > 0; day = day.AddDate(0, 0, -1) {
+		current++
+	}
+	return current, longest
+}
+
+func (s *Service) streakHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	counts, err := s.store.GetNoteCreationCountByDay(m.Message.From.ID, streakDays)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, err)
+		return
+	}
+
+	current, longest := computeStreaks(counts, time.Now(), streakDays)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Current streak: %d days 🔥 Longest: %d days", current, longest),
+	})
+}
+
+// creationTime formats when the user first signed in.
+func (s *Service) creationTime(userID int64) string {
+	createdAt, err := s.store.GetUserCreationTime(userID)
+	if err != nil {
+		return "unknown"
+	}
+	return createdAt.Format("2006-01-02 15:04")
+}
+
+func (s *Service) noteSizeHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	memoId, ok := memoIDArg(m.Message.Text, "note_size")
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /note_size <id>",
+		})
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+
+	var total int64
+	for _, attachment := range memo.Attachments {
+		total += int64(attachment.Size)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Memo #%d has %d attachments totalling %s", memo.ID, len(memo.Attachments), formatBytes(total)),
+	})
+}
+
+// formatBytes formats a size in bytes with SI units, e.g. "8.4 MB".
+func formatBytes(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	units := []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// mediaGroup is the memo created for the first message of a media group.
+type mediaGroup struct {
+	MemoID    int       `json:"memoID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// GetMediaGroupMemoID returns the memo created for the media group.
+func (s *Store) GetMediaGroupMemoID(groupID string) (int, bool) {
+	s.mediaGroupMutex.Lock()
+	defer s.mediaGroupMutex.Unlock()
+
+	group, ok := s.mediaGroups[groupID]
+	if !ok || time.Now().After(group.ExpiresAt) {
+		return 0, false
+	}
+	return group.MemoID, true
+}
+
+// SetMediaGroupMemoID records the memo created for the media group for ttl.
+func (s *Store) SetMediaGroupMemoID(groupID string, memoID int, ttl time.Duration) {
+	s.mediaGroupMutex.Lock()
+	defer s.mediaGroupMutex.Unlock()
+
+	now := time.Now()
+	for id, group := range s.mediaGroups {
+		if now.After(group.ExpiresAt) {
+			delete(s.mediaGroups, id)
+		}
+	}
+	s.mediaGroups[groupID] = mediaGroup{MemoID: memoID, ExpiresAt: now.Add(ttl)}
+	if err := s.saveMediaGroupsToFile(); err != nil {
+		slog.Error("failed to save media groups to file", "error", err)
+	}
+}
+
+// DeleteMediaGroups forgets the media groups whose ID starts with prefix,
+// and the expired ones. It returns how many were deleted.
+func (s *Store) DeleteMediaGroups(prefix string) int {
+	s.mediaGroupMutex.Lock()
+	defer s.mediaGroupMutex.Unlock()
+
+	count := 0
+	now := time.Now()
+	for id, group := range s.mediaGroups {
+		if strings.HasPrefix(id, prefix) || now.After(group.ExpiresAt) {
+			delete(s.mediaGroups, id)
+			count++
+		}
+	}
+	if count > 0 {
+		if err := s.saveMediaGroupsToFile(); err != nil {
+			slog.Error("failed to save media groups to file", "error", err)
+		}
+	}
+	return count
+}
+
+func (s *Store) saveMediaGroupsToFile() error {
+	data, err := json.MarshalIndent(s.mediaGroups, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.MediaGroup, data, 0644)
+}
+
+func (s *Store) loadMediaGroupsFromFile() error {
+	data, err := os.ReadFile(s.MediaGroup)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, &s.mediaGroups)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+)
+
+// SetNoteOwner records the user who created the note through the bot.
+func (s *Store) SetNoteOwner(noteID int, userID int64) {
+	s.noteOwnerMutex.Lock()
+	defer s.noteOwnerMutex.Unlock()
+
+	s.noteOwners[noteID] = userID
+	if err := s.saveNoteOwnersToFile(); err != nil {
+		slog.Error("failed to save note owners to file", "error", err)
+	}
+}
+
+// GetUserByNoteID returns the user who created the note through the bot.
+func (s *Store) GetUserByNoteID(noteID int) (int64, bool) {
+	s.noteOwnerMutex.Lock()
+	defer s.noteOwnerMutex.Unlock()
+
+	userID, ok := s.noteOwners[noteID]
+	return userID, ok
+}
+
+func (s *Store) saveNoteOwnersToFile() error {
+	data, err := json.MarshalIndent(s.noteOwners, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.NoteOwner, data, 0644)
+}
+
+func (s *Store) loadNoteOwnersFromFile() error {
+	data, err := os.ReadFile(s.NoteOwner)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, &s.noteOwners)
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// MarkRead records that the user reviewed the note.
+func (s *Store) MarkRead(userID int64, noteID int) {
+	s.readMemoMutex.Lock()
+	defer s.readMemoMutex.Unlock()
+
+	if s.readMemos[userID] == nil {
+		s.readMemos[userID] = map[int]time.Time{}
+	}
+	s.readMemos[userID][noteID] = time.Now()
+	if err := s.saveReadMemosToFile(); err != nil {
+		slog.Error("failed to save read memos to file", "error", err)
+	}
+}
+
+// MarkUnread removes the read record of the note. It returns false if the
+// note was not marked as read.
+func (s *Store) MarkUnread(userID int64, noteID int) bool {
+	s.readMemoMutex.Lock()
+	defer s.readMemoMutex.Unlock()
+
+	if _, ok := s.readMemos[userID][noteID]; !ok {
+		return false
+	}
+	delete(s.readMemos[userID], noteID)
+	if len(s.readMemos[userID]) == 0 {
+		delete(s.readMemos, userID)
+	}
+	if err := s.saveReadMemosToFile(); err != nil {
+		slog.Error("failed to save read memos to file", "error", err)
+	}
+	return true
+}
+
+// IsRead reports whether the user marked the note as read.
+func (s *Store) IsRead(userID int64, noteID int) bool {
+	s.readMemoMutex.Lock()
+	defer s.readMemoMutex.Unlock()
+
+	_, ok := s.readMemos[userID][noteID]
+	return ok
+}
+
+func (s *Store) saveReadMemosToFile() error {
+	data, err := json.MarshalIndent(s.readMemos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.ReadMemo, data, 0644)
+}
+
+func (s *Store) loadReadMemosFromFile() error {
+	data, err := os.ReadFile(s.ReadMemo)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, &s.readMemos)
+}
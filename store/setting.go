@@ -0,0 +1,353 @@
+package store
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// UserSetting holds the per-user preferences of the bot.
+type UserSetting struct {
+	Locale string `json:"locale,omitempty"`
+	// DailyDigestTime is the time of day formatted as "15:04".
+	DailyDigestTime  string         `json:"dailyDigestTime,omitempty"`
+	Transform        string         `json:"transform,omitempty"`
+	Timezone         string         `json:"timezone,omitempty"`
+	ServerURL        string         `json:"serverURL,omitempty"`
+	NotesCreated     int            `json:"notesCreated,omitempty"`
+	MessageCount     int            `json:"messageCount,omitempty"`
+	RefreshToken     string         `json:"refreshToken,omitempty"`
+	DefaultNotebook  int            `json:"defaultNotebook,omitempty"`
+	ChatID           int64          `json:"chatID,omitempty"`
+	TagSubscriptions []string       `json:"tagSubscriptions,omitempty"`
+	Sandbox          bool           `json:"sandbox,omitempty"`
+	CreatedAt        *time.Time     `json:"createdAt,omitempty"`
+	NotifyOnShare    bool           `json:"notifyOnShare,omitempty"`
+	LastNoteID       int            `json:"lastNoteID,omitempty"`
+	NotesByDay       map[string]int `json:"notesByDay,omitempty"`
+}
+
+const dailyDigestTimeLayout = "15:04"
+
+// GetUserLocale returns the locale for the user, or an empty string if unset.
+func (s *Store) GetUserLocale(userID int64) string {
+	return s.getUserSetting(userID).Locale
+}
+
+// SetUserLocale sets the locale for the user.
+func (s *Store) SetUserLocale(userID int64, locale string) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.Locale = locale
+	})
+}
+
+// GetUserTransform returns the name of the content transform for the user.
+func (s *Store) GetUserTransform(userID int64) string {
+	return s.getUserSetting(userID).Transform
+}
+
+// SetUserTransform sets the name of the content transform for the user.
+func (s *Store) SetUserTransform(userID int64, transform string) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.Transform = transform
+	})
+}
+
+// GetUserChatID returns the chat the user started the bot in. For users
+// who started the bot before it was recorded, this is the private chat,
+// whose ID is the user ID.
+func (s *Store) GetUserChatID(userID int64) int64 {
+	if chatID := s.getUserSetting(userID).ChatID; chatID != 0 {
+		return chatID
+	}
+	return userID
+}
+
+// SetUserChatID sets the chat the user started the bot in.
+func (s *Store) SetUserChatID(userID int64, chatID int64) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.ChatID = chatID
+	})
+}
+
+// GetTagSubscriptions returns the tags the user subscribed to.
+func (s *Store) GetTagSubscriptions(userID int64) []string {
+	return s.getUserSetting(userID).TagSubscriptions
+}
+
+// AddTagSubscription subscribes the user to the tag.
+func (s *Store) AddTagSubscription(userID int64, tag string) {
+	tag = strings.ToLower(tag)
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		if !slices.Contains(setting.TagSubscriptions, tag) {
+			setting.TagSubscriptions = append(setting.TagSubscriptions, tag)
+		}
+	})
+}
+
+// RemoveTagSubscription unsubscribes the user from the tag.
+func (s *Store) RemoveTagSubscription(userID int64, tag string) {
+	tag = strings.ToLower(tag)
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.TagSubscriptions = slices.DeleteFunc(setting.TagSubscriptions, func(subscription string) bool {
+			return subscription == tag
+		})
+	})
+}
+
+// GetTagSubscribers returns the users subscribed to the tag.
+func (s *Store) GetTagSubscribers(tag string) ([]int64, error) {
+	tag = strings.ToLower(tag)
+	var userIDs []int64
+	s.userSettingCache.Range(func(key, value interface{}) bool {
+		if slices.Contains(value.(UserSetting).TagSubscriptions, tag) {
+			userIDs = append(userIDs, key.(int64))
+		}
+		return true
+	})
+	slices.Sort(userIDs)
+	return userIDs, nil
+}
+
+// GetUserSandbox reports whether the user is in sandbox mode.
+func (s *Store) GetUserSandbox(userID int64) bool {
+	return s.getUserSetting(userID).Sandbox
+}
+
+// SetUserSandbox turns sandbox mode on or off for the user.
+func (s *Store) SetUserSandbox(userID int64, sandbox bool) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.Sandbox = sandbox
+	})
+}
+
+// GetUserNotifyOnShare reports whether the user wants to know when their
+// public notes are viewed.
+func (s *Store) GetUserNotifyOnShare(userID int64) bool {
+	return s.getUserSetting(userID).NotifyOnShare
+}
+
+// SetUserNotifyOnShare turns the view notifications on or off for the user.
+func (s *Store) SetUserNotifyOnShare(userID int64, notify bool) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.NotifyOnShare = notify
+	})
+}
+
+// GetLastNoteID returns the last note the user created through the bot.
+func (s *Store) GetLastNoteID(userID int64) (int, bool) {
+	id := s.getUserSetting(userID).LastNoteID
+	return id, id != 0
+}
+
+// SetLastNoteID records the last note the user created through the bot.
+func (s *Store) SetLastNoteID(userID int64, noteID int) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.LastNoteID = noteID
+	})
+}
+
+const (
+	noteDayLayout = "2006-01-02"
+	// noteDayRetention is how many days of note counts are kept.
+	noteDayRetention = 400
+)
+
+// RecordNoteCreation counts a note created by the user at createdAt.
+func (s *Store) RecordNoteCreation(userID int64, createdAt time.Time) {
+	cutoff := time.Now().AddDate(0, 0, -noteDayRetention).Format(noteDayLayout)
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		if setting.NotesByDay == nil {
+			setting.NotesByDay = map[string]int{}
+		}
+		for day := range setting.NotesByDay {
+			if day < cutoff {
+				delete(setting.NotesByDay, day)
+			}
+		}
+		setting.NotesByDay[createdAt.Format(noteDayLayout)]++
+	})
+}
+
+// GetNoteCreationCountByDay returns how many notes the user created on each
+// of the last days, today included, keyed by "YYYY-MM-DD". Days without
+// notes are left out.
+func (s *Store) GetNoteCreationCountByDay(userID int64, days int) (map[string]int, error) {
+	cutoff := time.Now().AddDate(0, 0, 1-days).Format(noteDayLayout)
+	counts := map[string]int{}
+	for day, count := range s.getUserSetting(userID).NotesByDay {
+		if day >= cutoff {
+			counts[day] = count
+		}
+	}
+	return counts, nil
+}
+
+// GetUserDefaultNotebook returns the ID of the notebook new memos of the
+// user are saved to, or 0 if unset.
+func (s *Store) GetUserDefaultNotebook(userID int64) int {
+	return s.getUserSetting(userID).DefaultNotebook
+}
+
+// SetUserDefaultNotebook sets the notebook new memos of the user are saved to.
+func (s *Store) SetUserDefaultNotebook(userID int64, id int) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.DefaultNotebook = id
+	})
+}
+
+// GetUserRefreshToken returns the refresh token for the user.
+func (s *Store) GetUserRefreshToken(userID int64) string {
+	return s.getUserSetting(userID).RefreshToken
+}
+
+// SetUserTokens sets the access token and the refresh token for the user.
+func (s *Store) SetUserTokens(userID int64, accessToken, refreshToken string) {
+	s.SetUserAccessToken(userID, accessToken)
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.RefreshToken = refreshToken
+	})
+}
+
+// GetUserTimezone returns the IANA timezone name for the user.
+func (s *Store) GetUserTimezone(userID int64) string {
+	return s.getUserSetting(userID).Timezone
+}
+
+// SetUserTimezone sets the IANA timezone name for the user.
+func (s *Store) SetUserTimezone(userID int64, timezone string) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.Timezone = timezone
+	})
+}
+
+// GetUserServerURL returns the Blinko server URL override for the user.
+func (s *Store) GetUserServerURL(userID int64) string {
+	return s.getUserSetting(userID).ServerURL
+}
+
+// SetUserServerURL sets the Blinko server URL override for the user.
+func (s *Store) SetUserServerURL(userID int64, serverURL string) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.ServerURL = serverURL
+	})
+}
+
+// GetNotesCreated returns the number of memos the user created through the bot.
+func (s *Store) GetNotesCreated(userID int64) int {
+	return s.getUserSetting(userID).NotesCreated
+}
+
+// IncrementNotesCreated increments the number of memos the user created.
+func (s *Store) IncrementNotesCreated(userID int64) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.NotesCreated++
+	})
+}
+
+// GetUserMessageCount returns the number of messages of the user saved to
+// Blinko, counting every part of a media group.
+func (s *Store) GetUserMessageCount(userID int64) (int, error) {
+	return s.getUserSetting(userID).MessageCount, nil
+}
+
+// IncrementUserMessageCount increments the number of messages of the user
+// saved to Blinko.
+func (s *Store) IncrementUserMessageCount(userID int64) error {
+	return s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.MessageCount++
+	})
+}
+
+// GetDailyDigestTime returns the time of day the user receives the daily digest.
+func (s *Store) GetDailyDigestTime(userID int64) (time.Time, bool) {
+	return parseDailyDigestTime(s.getUserSetting(userID).DailyDigestTime)
+}
+
+// SetDailyDigestTime sets the time of day the user receives the daily digest.
+// Only the hour and minute of t are kept.
+func (s *Store) SetDailyDigestTime(userID int64, t time.Time) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.DailyDigestTime = t.Format(dailyDigestTimeLayout)
+	})
+}
+
+// DeleteDailyDigestTime cancels the daily digest for the user.
+func (s *Store) DeleteDailyDigestTime(userID int64) {
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.DailyDigestTime = ""
+	})
+}
+
+func parseDailyDigestTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(dailyDigestTimeLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (s *Store) getUserSetting(userID int64) UserSetting {
+	setting, ok := s.userSettingCache.Load(userID)
+	if !ok {
+		return UserSetting{}
+	}
+	return setting.(UserSetting)
+}
+
+func (s *Store) updateUserSetting(userID int64, update func(setting *UserSetting)) error {
+	s.userSettingMutex.Lock()
+	defer s.userSettingMutex.Unlock()
+
+	setting := s.getUserSetting(userID)
+	update(&setting)
+	s.userSettingCache.Store(userID, setting)
+	if err := s.saveUserSettingMapToFile(); err != nil {
+		slog.Error("failed to save user setting map to file", "error", err)
+		return err
+	}
+	return nil
+}
+
+// saveUserSettingMapToFile saves the user setting map to the setting file.
+func (s *Store) saveUserSettingMapToFile() error {
+	settings := map[int64]UserSetting{}
+	s.userSettingCache.Range(func(key, value interface{}) bool {
+		settings[key.(int64)] = value.(UserSetting)
+		return true
+	})
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Setting, data, 0644)
+}
+
+func (s *Store) loadUserSettingMapFromFile() error {
+	data, err := os.ReadFile(s.Setting)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	settings := map[int64]UserSetting{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	for userID, setting := range settings {
+		s.userSettingCache.Store(userID, setting)
+	}
+	return nil
+}
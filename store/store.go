@@ -1,22 +1,55 @@
 package store
 
 import (
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 type Store struct {
-	Data string
+	Data       string
+	Setting    string
+	MediaGroup string
+	NoteOwner  string
+	Template   string
+	ReadMemo   string
 
 	userAccessTokenCache sync.Map // map[int64]string
+	userSettingCache     sync.Map // map[int64]UserSetting
+	userSettingMutex     sync.Mutex
+	mediaGroups          map[string]mediaGroup
+	mediaGroupMutex      sync.Mutex
+	noteOwners           map[int]int64
+	noteOwnerMutex       sync.Mutex
+	templates            map[int64]map[string]string
+	templateMutex        sync.Mutex
+	readMemos            map[int64]map[int]time.Time
+	readMemoMutex        sync.Mutex
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 func NewStore(data string) *Store {
+	base := strings.TrimSuffix(data, filepath.Ext(data))
 	return &Store{
 		Data: data,
+		// Settings live next to the data file, e.g. `data.txt` -> `data.settings.json`.
+		Setting:    base + ".settings.json",
+		MediaGroup: base + ".media_groups.json",
+		NoteOwner:  base + ".note_owners.json",
+		Template:   base + ".templates.json",
+		ReadMemo:   base + ".read_memos.json",
 
 		userAccessTokenCache: sync.Map{},
+		userSettingCache:     sync.Map{},
+		mediaGroups:          map[string]mediaGroup{},
+		noteOwners:           map[int]int64{},
+		templates:            map[int64]map[string]string{},
+		readMemos:            map[int64]map[int]time.Time{},
 	}
 }
 
@@ -24,6 +57,73 @@ func (s *Store) Init() error {
 	if err := s.loadUserAccessTokenMapFromFile(); err != nil {
 		return errors.Wrap(err, "failed to load user access token map from file")
 	}
+	if err := s.loadUserSettingMapFromFile(); err != nil {
+		return errors.Wrap(err, "failed to load user setting map from file")
+	}
+	if err := s.loadMediaGroupsFromFile(); err != nil {
+		return errors.Wrap(err, "failed to load media groups from file")
+	}
+	if err := s.loadNoteOwnersFromFile(); err != nil {
+		return errors.Wrap(err, "failed to load note owners from file")
+	}
+	if err := s.loadTemplatesFromFile(); err != nil {
+		return errors.Wrap(err, "failed to load templates from file")
+	}
+	if err := s.loadReadMemosFromFile(); err != nil {
+		return errors.Wrap(err, "failed to load read memos from file")
+	}
 
 	return nil
 }
+
+// Close writes every file of the store a last time. The files are already
+// saved on every change, so this only guards against a failed earlier
+// write. Calling Close again returns the result of the first call.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		if err := s.SaveUserAccessTokenMapToFile(); err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save user access token map to file")
+			return
+		}
+
+		s.userSettingMutex.Lock()
+		err := s.saveUserSettingMapToFile()
+		s.userSettingMutex.Unlock()
+		if err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save user setting map to file")
+			return
+		}
+
+		s.mediaGroupMutex.Lock()
+		err = s.saveMediaGroupsToFile()
+		s.mediaGroupMutex.Unlock()
+		if err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save media groups to file")
+			return
+		}
+
+		s.noteOwnerMutex.Lock()
+		err = s.saveNoteOwnersToFile()
+		s.noteOwnerMutex.Unlock()
+		if err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save note owners to file")
+			return
+		}
+
+		s.templateMutex.Lock()
+		err = s.saveTemplatesToFile()
+		s.templateMutex.Unlock()
+		if err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save templates to file")
+			return
+		}
+
+		s.readMemoMutex.Lock()
+		err = s.saveReadMemosToFile()
+		s.readMemoMutex.Unlock()
+		if err != nil {
+			s.closeErr = errors.Wrap(err, "failed to save read memos to file")
+		}
+	})
+	return s.closeErr
+}
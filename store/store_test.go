@@ -0,0 +1,59 @@
+package store
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCloseTwice(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "data.txt")
+	s := NewStore(data)
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	s.SetUserTokens(42, "access", "refresh")
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("first Close() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+
+	reopened := NewStore(data)
+	if err := reopened.Init(); err != nil {
+		t.Fatalf("Init() after Close() error = %v", err)
+	}
+	if token, ok := reopened.GetUserAccessToken(42); !ok || token != "access" {
+		t.Errorf("GetUserAccessToken(42) = %q, %v, want %q, true", token, ok, "access")
+	}
+	if token := reopened.GetUserRefreshToken(42); token != "refresh" {
+		t.Errorf("GetUserRefreshToken(42) = %q, want %q", token, "refresh")
+	}
+}
+
+func TestListAllUsers(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "data.txt"))
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	userIDs, err := s.ListAllUsers()
+	if err != nil || len(userIDs) != 0 {
+		t.Fatalf("ListAllUsers() = %v, %v, want no users", userIDs, err)
+	}
+
+	for _, userID := range []int64{30, 10, 20} {
+		s.SetUserAccessToken(userID, "token")
+	}
+	s.DeleteUserAccessToken(20)
+
+	userIDs, err = s.ListAllUsers()
+	if err != nil {
+		t.Fatalf("ListAllUsers() error = %v", err)
+	}
+	if want := []int64{10, 30}; !slices.Equal(userIDs, want) {
+		t.Errorf("ListAllUsers() = %v, want %v", userIDs, want)
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+// SaveTemplate stores the content as the user's template with the name,
+// replacing any template with the same name.
+func (s *Store) SaveTemplate(userID int64, name, content string) {
+	s.templateMutex.Lock()
+	defer s.templateMutex.Unlock()
+
+	if s.templates[userID] == nil {
+		s.templates[userID] = map[string]string{}
+	}
+	s.templates[userID][name] = content
+	if err := s.saveTemplatesToFile(); err != nil {
+		slog.Error("failed to save templates to file", "error", err)
+	}
+}
+
+// GetTemplate returns the content of the user's template with the name.
+func (s *Store) GetTemplate(userID int64, name string) (string, bool) {
+	s.templateMutex.Lock()
+	defer s.templateMutex.Unlock()
+
+	content, ok := s.templates[userID][name]
+	return content, ok
+}
+
+// ListTemplates returns the names of the user's templates, sorted.
+func (s *Store) ListTemplates(userID int64) []string {
+	s.templateMutex.Lock()
+	defer s.templateMutex.Unlock()
+
+	names := make([]string, 0, len(s.templates[userID]))
+	for name := range s.templates[userID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Store) saveTemplatesToFile() error {
+	data, err := json.MarshalIndent(s.templates, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Template, data, 0644)
+}
+
+func (s *Store) loadTemplatesFromFile() error {
+	data, err := os.ReadFile(s.Template)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, &s.templates)
+}
@@ -4,8 +4,12 @@ import (
 	"bufio"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // GetUserAccessToken returns the access token for the user.
@@ -17,12 +21,55 @@ func (s *Store) GetUserAccessToken(userID int64) (string, bool) {
 	return accessToken.(string), true
 }
 
-// SetUserAccessToken sets the access token for the user.
+// ErrUnknownCreationTime is returned for users who signed in before the
+// creation time was recorded.
+var ErrUnknownCreationTime = errors.New("user creation time unknown")
+
+// SetUserAccessToken sets the access token for the user. The first time a
+// token is set, the creation time of the user is recorded.
 func (s *Store) SetUserAccessToken(userID int64, accessToken string) {
 	s.userAccessTokenCache.Store(userID, accessToken)
 	if err := s.SaveUserAccessTokenMapToFile(); err != nil {
 		slog.Error("failed to save user access token map to file", "error", err)
 	}
+	if s.getUserSetting(userID).CreatedAt == nil {
+		now := time.Now()
+		s.updateUserSetting(userID, func(setting *UserSetting) {
+			setting.CreatedAt = &now
+		})
+	}
+}
+
+// GetUserCreationTime returns when the user first signed in.
+func (s *Store) GetUserCreationTime(userID int64) (time.Time, error) {
+	createdAt := s.getUserSetting(userID).CreatedAt
+	if createdAt == nil {
+		return time.Time{}, ErrUnknownCreationTime
+	}
+	return *createdAt, nil
+}
+
+// DeleteUserAccessToken removes the access token and refresh token for the user.
+func (s *Store) DeleteUserAccessToken(userID int64) {
+	s.userAccessTokenCache.Delete(userID)
+	if err := s.SaveUserAccessTokenMapToFile(); err != nil {
+		slog.Error("failed to save user access token map to file", "error", err)
+	}
+	s.updateUserSetting(userID, func(setting *UserSetting) {
+		setting.RefreshToken = ""
+	})
+}
+
+// ListAllUsers returns the IDs of all users with an access token, in
+// ascending order.
+func (s *Store) ListAllUsers() ([]int64, error) {
+	var userIDs []int64
+	s.userAccessTokenCache.Range(func(key, value interface{}) bool {
+		userIDs = append(userIDs, key.(int64))
+		return true
+	})
+	slices.Sort(userIDs)
+	return userIDs, nil
 }
 
 // SaveUserAccessTokenMapToFile saves the user access token map to a data file.
@@ -0,0 +1,115 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const subscriptionSnippetLength = 200
+
+func (s *Service) subscribeHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	tag := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/subscribe")), "#")
+
+	if tag == "" {
+		text := "Usage: /subscribe <tag>"
+		if tags := s.store.GetTagSubscriptions(userID); len(tags) > 0 {
+			text = "Subscribed tags: #" + strings.Join(tags, " #")
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   text,
+		})
+		return
+	}
+	if strings.ContainsAny(tag, " \t\n") {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /subscribe <tag>",
+		})
+		return
+	}
+
+	s.store.AddTagSubscription(userID, tag)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("You will be notified of new memos tagged #%s", strings.ToLower(tag)),
+	})
+}
+
+func (s *Service) unsubscribeHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	tag := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/unsubscribe")), "#")
+	if tag == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Usage: /unsubscribe <tag>",
+		})
+		return
+	}
+
+	s.store.RemoveTagSubscription(m.Message.From.ID, tag)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Unsubscribed from #%s", strings.ToLower(tag)),
+	})
+}
+
+// notifySubscribers tells the users subscribed to a tag of the memo that it
+// was created. Only users logged in to the same Blinko account as the creator
+// are notified, so that memos are never shown to other accounts.
+func (s *Service) notifySubscribers(ctx context.Context, b *bot.Bot, creatorID int64, memo BlinkoItem) {
+	tags := collectTags([]BlinkoItem{memo})
+	if len(tags) == 0 {
+		return
+	}
+	creator, err := s.getUserDetail(s.newUserClient(ctx, creatorID), creatorID)
+	if err != nil {
+		slog.Error("failed to get creator of memo", slog.Int("memo_id", memo.ID), slog.Any("err", err))
+		return
+	}
+
+	notified := map[int64]bool{creatorID: true}
+	for _, tag := range tags {
+		subscribers, err := s.store.GetTagSubscribers(tag)
+		if err != nil {
+			slog.Error("failed to get tag subscribers", slog.String("tag", tag), slog.Any("err", err))
+			continue
+		}
+		for _, subscriberID := range subscribers {
+			if notified[subscriberID] {
+				continue
+			}
+			notified[subscriberID] = true
+			if !s.sameAccount(ctx, creatorID, creator, subscriberID) {
+				continue
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: s.store.GetUserChatID(subscriberID),
+				Text:   fmt.Sprintf("New memo #%d tagged #%s:\n%s", memo.ID, tag, truncateRunes(memo.Content, subscriptionSnippetLength)),
+			})
+		}
+	}
+}
+
+// sameAccount reports whether the subscriber is logged in to the Blinko
+// account of the creator. The subscriber is looked up with a client of its
+// own, the creator's update may still be using its client.
+func (s *Service) sameAccount(ctx context.Context, creatorID int64, creator UserInfo, subscriberID int64) bool {
+	if s.serverAddr(subscriberID) != s.serverAddr(creatorID) {
+		return false
+	}
+	if _, ok := s.store.GetUserAccessToken(subscriberID); !ok {
+		return false
+	}
+	subscriber, err := s.getUserDetail(s.newUserClient(ctx, subscriberID), subscriberID)
+	if err != nil {
+		slog.Warn("failed to get subscriber account", slog.Int64("user_id", subscriberID), slog.Any("err", err))
+		return false
+	}
+	return subscriber.ID == creator.ID
+}
@@ -0,0 +1,87 @@
+package blinkogram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const summaryTimeout = 30 * time.Second
+
+// SummaryClient summarizes long memos with the LLM service at
+// SUMMARIZE_API_URL. The service receives {"content": "..."} and answers
+// {"summary": "..."}.
+type SummaryClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewSummaryClient(url string) *SummaryClient {
+	return &SummaryClient{
+		url:        url,
+		httpClient: &http.Client{Timeout: summaryTimeout},
+	}
+}
+
+// Summarize returns the summary of the content.
+func (c *SummaryClient) Summarize(ctx context.Context, content string) (string, error) {
+	jsonBody, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send summarize request")
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read summarize response")
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("summarize request failed: %d %s", res.StatusCode, body)
+	}
+
+	var result struct {
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", errors.Wrap(err, "failed to decode summarize response")
+	}
+	return strings.TrimSpace(result.Summary), nil
+}
+
+// summarize prepends a blockquote with the summary of the content when it
+// is longer than SUMMARIZE_THRESHOLD_CHARS. The content is kept unchanged
+// when summarizing fails.
+func (s *Service) summarize(ctx context.Context, content string) string {
+	if s.summaryClient == nil || utf8.RuneCountInString(content) <= s.config.SummarizeThresholdChars {
+		return content
+	}
+
+	summary, err := s.summaryClient.Summarize(ctx, content)
+	if err != nil {
+		slog.Warn("failed to summarize memo", slog.Any("err", err))
+		return content
+	}
+	if summary == "" {
+		return content
+	}
+	return "> " + strings.ReplaceAll(summary, "\n", "\n> ") + "\n\n" + content
+}
@@ -0,0 +1,86 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/pkg/errors"
+)
+
+// hashtagRegexp matches the #hashtags of a memo, but not the fragments of
+// URLs.
+var hashtagRegexp = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+
+// collectTags returns the unique hashtags used in the memos, sorted.
+func collectTags(memos []BlinkoItem) []string {
+	seen := map[string]bool{}
+	for _, memo := range memos {
+		for _, match := range hashtagRegexp.FindAllStringSubmatch(memo.Content, -1) {
+			seen[match[1]] = true
+		}
+	}
+
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	return tags
+}
+
+// formatTagList lists the sorted tags with one line per first letter.
+func formatTagList(tags []string) string {
+	var sb strings.Builder
+	var group rune
+	for i, tag := range tags {
+		first := unicode.ToUpper([]rune(tag)[0])
+		if i == 0 || first != group {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			group = first
+			sb.WriteString(fmt.Sprintf("%c: ", group))
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("#" + tag)
+	}
+	return sb.String()
+}
+
+func (s *Service) tagListHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memos, err := client.GetAllNotes()
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, errors.Wrap(err, "failed to get memos"))
+		return
+	}
+
+	tags := collectTags(memos)
+	if len(tags) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "No tags found",
+		})
+		return
+	}
+
+	for _, part := range splitMessage(formatTagList(tags), s.config.MessageMaxLength) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   part,
+		})
+	}
+}
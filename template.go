@@ -0,0 +1,111 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const templateUsage = "Usage: /template save <name>, /template use <name> [key=value ...] or /template list"
+
+func (s *Service) templateHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	args := strings.Fields(strings.TrimPrefix(m.Message.Text, "/template"))
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		s.listTemplates(ctx, b, m)
+	case len(args) == 2 && args[0] == "save":
+		s.saveTemplate(ctx, b, m, args[1])
+	case len(args) >= 2 && args[0] == "use":
+		s.useTemplate(ctx, b, m, args[1], args[2:])
+	default:
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   templateUsage,
+		})
+	}
+}
+
+func (s *Service) listTemplates(ctx context.Context, b *bot.Bot, m *models.Update) {
+	text := "No templates saved yet. Use /template save <name> after creating a memo."
+	if names := s.store.ListTemplates(m.Message.From.ID); len(names) > 0 {
+		text = "Templates: " + strings.Join(names, ", ")
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   text,
+	})
+}
+
+// saveTemplate saves the content of the last memo the user created as a
+// template.
+func (s *Service) saveTemplate(ctx context.Context, b *bot.Bot, m *models.Update, name string) {
+	userID := m.Message.From.ID
+	memoId, ok := s.store.GetLastNoteID(userID)
+	if !ok {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError("Create a memo first, its content is saved as the template", nil))
+		return
+	}
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+
+	memo, err := client.GetNoteDetail(memoId)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to get memo %d", memoId), err))
+		return
+	}
+	if _, err := template.New(name).Parse(memo.Content); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Memo %d is not a valid template", memoId), err))
+		return
+	}
+
+	s.store.SaveTemplate(userID, name, memo.Content)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Saved memo #%d as template %q", memoId, name),
+	})
+}
+
+// useTemplate creates a memo from the template, replacing the {{.key}}
+// placeholders with the key=value arguments.
+func (s *Service) useTemplate(ctx context.Context, b *bot.Bot, m *models.Update, name string, args []string) {
+	userID := m.Message.From.ID
+	text, ok := s.store.GetTemplate(userID, name)
+	if !ok {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Template %q not found", name), nil))
+		return
+	}
+
+	variables := map[string]string{}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Invalid variable %q, expected key=value", arg), nil))
+			return
+		}
+		variables[key] = value
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Template %q is invalid", name), err))
+		return
+	}
+	var content strings.Builder
+	if err := tmpl.Execute(&content, variables); err != nil {
+		s.sendError(b, m.Message.Chat.ID, m.Message.From.ID, NewUserError(fmt.Sprintf("Failed to fill template %q, check the variables", name), err))
+		return
+	}
+
+	client, ok := s.authorize(ctx, b, m)
+	if !ok {
+		return
+	}
+	tags := collectTags([]BlinkoItem{{Content: content.String()}})
+	s.saveMemo(ctx, b, client, m, content.String(), tags)
+}
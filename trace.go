@@ -0,0 +1,33 @@
+package blinkogram
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type correlationIDKey struct{}
+
+// newCorrelationID returns a random UUID (version 4).
+func newCorrelationID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// withCorrelationID returns a copy of ctx carrying the correlation ID.
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationIDFromContext returns the correlation ID carried by ctx.
+func correlationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
@@ -0,0 +1,91 @@
+package blinkogram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// UserPreferences holds the per-user behaviour applied to new memos.
+type UserPreferences struct {
+	// Transform post-processes the memo content before it is saved.
+	Transform func(content string) string
+}
+
+// transforms is the registry of the content transforms selectable with /transform.
+var transforms = map[string]func(string) string{
+	"uppercase":     strings.ToUpper,
+	"lowercase":     strings.ToLower,
+	"trim":          strings.TrimSpace,
+	"sentence_case": sentenceCase,
+}
+
+// sentenceCase lowercases the content and capitalizes the first letter of
+// every sentence.
+func sentenceCase(content string) string {
+	runes := []rune(strings.ToLower(content))
+	capitalize := true
+	for i, r := range runes {
+		switch {
+		case r == '.' || r == '!' || r == '?' || r == '\n':
+			capitalize = true
+		case capitalize && unicode.IsLetter(r):
+			runes[i] = unicode.ToUpper(r)
+			capitalize = false
+		case !unicode.IsSpace(r):
+			capitalize = false
+		}
+	}
+	return string(runes)
+}
+
+func transformNames() []string {
+	names := make([]string, 0, len(transforms))
+	for name := range transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userPreferences returns the preferences of the user.
+func (s *Service) userPreferences(userID int64) UserPreferences {
+	preferences := UserPreferences{}
+	if transform, ok := transforms[s.store.GetUserTransform(userID)]; ok {
+		preferences.Transform = transform
+	}
+	return preferences
+}
+
+func (s *Service) transformHandler(ctx context.Context, b *bot.Bot, m *models.Update) {
+	userID := m.Message.From.ID
+	name := strings.TrimSpace(strings.TrimPrefix(m.Message.Text, "/transform"))
+
+	if name == "off" {
+		s.store.SetUserTransform(userID, "")
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   "Transform disabled",
+		})
+		return
+	}
+
+	if _, ok := transforms[name]; !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: m.Message.Chat.ID,
+			Text:   fmt.Sprintf("Usage: /transform <name> or /transform off. Available: %s", strings.Join(transformNames(), ", ")),
+		})
+		return
+	}
+
+	s.store.SetUserTransform(userID, name)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: m.Message.Chat.ID,
+		Text:   fmt.Sprintf("Transform set to %s", name),
+	})
+}
@@ -0,0 +1,65 @@
+package blinkogram
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// parseVersion splits a version such as "v1.2.3-beta" into its numeric
+// parts. Non-numeric suffixes are ignored.
+func parseVersion(version string) []int {
+	var parts []int
+	for _, field := range strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".") {
+		digits := strings.IndexFunc(field, func(r rune) bool { return r < '0' || r > '9' })
+		if digits >= 0 {
+			field = field[:digits]
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// compareVersions returns -1, 0 or 1 when a is older than, equal to or
+// newer than b. Missing parts count as 0.
+func compareVersions(a, b string) int {
+	pa, pb := parseVersion(a), parseVersion(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkServerVersion logs the version of the Blinko server and warns when
+// it is older than MIN_SERVER_VERSION.
+func (s *Service) checkServerVersion() {
+	client := NewBlinkoClient(s.config.ServerAddr, s.clientOptionsFor(context.Background())...)
+	version, err := client.GetServerVersion()
+	if err != nil {
+		slog.Warn("failed to get blinko server version", slog.Any("err", err))
+		return
+	}
+	slog.Info("connected to blinko server", slog.String("version", version))
+
+	if minVersion := s.config.MinServerVersion; minVersion != "" && compareVersions(version, minVersion) < 0 {
+		slog.Warn("blinko server is older than the minimum supported version, some features may not work",
+			slog.String("version", version), slog.String("min_version", minVersion))
+	}
+}